@@ -1,16 +1,18 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -19,22 +21,58 @@ import (
 
 // Config strukturen (unverändert)
 type Config struct {
-	Logs []LogConfig `yaml:"logs"`
+	Logs            []LogConfig `yaml:"logs"`
+	DisplayTimezone string      `yaml:"displaytimezone"` // local, UTC oder z.B. "Europe/Berlin"
+	Language        string      `yaml:"language"`        // de oder en, leer nutzt LANG
+	Theme           string      `yaml:"theme"`           // dark, light oder auto
+	Metrics         []MetricConfig `yaml:"metrics"`
+	Sinks           []SinkConfig   `yaml:"sinks"`
+	Redaction       RedactionConfig `yaml:"redaction"`
+	Index           string          `yaml:"index"` // Datei für den Index, leer liest Dateien immer neu
+	Plugins         []PluginConfig  `yaml:"plugins"` // externe Parser
+	Views           []ViewConfig    `yaml:"views"`   // gespeicherte Ansichten
+	Enrichment      EnrichmentConfig `yaml:"enrichment"` // GeoIP, User-Agent, Reverse-DNS und Netz-Labels
+
+	path       string // Pfad der geladenen config.yaml
+	displayLoc *time.Location
+	redactor   *redactor
+	enricher   *enricher
 }
 
 type LogConfig struct {
-	Path     string `yaml:"path"`
-	Type     string `yaml:"type"`
+	Path     string `yaml:"path"`    // "-" liest von stdin
+	Command  string `yaml:"command"` // z.B. "docker logs -f web"
+	Type     string `yaml:"type"` // leer oder "auto" erkennt das Format
 	LogLevel string `yaml:"loglevel"`
-	Color    string `yaml:"color"`
+	Color    string `yaml:"color"` // Name, Hex-Wert oder ANSI-Nummer
+
+	// Zeitstempel: Go-Layout oder PHP-Format (wie Nextclouds logdateformat)
+	// und die Zeitzone der Quelle für Zeitstempel ohne Zonenangabe
+	TimeFormat    string `yaml:"timeformat"`
+	LogDateFormat string `yaml:"logdateformat"`
+	Timezone      string `yaml:"timezone"`
+
+	// log_format von nginx, leer nutzt "combined"
+	LogFormat string `yaml:"logformat"`
+
+	// Spaltenlayout der Tabellenansicht
+	Columns []ColumnConfig `yaml:"columns"`
+
+	// Regeln, die das Level des Parsers vor dem Filtern nach loglevel ersetzen
+	Severity []SeverityRule `yaml:"severity"`
+
+	index         string         // Index-Datei aus der Config
+	plugin        *PluginConfig  // externer Parser, wenn type ein Plugin nennt
+	enricher      *enricher      // gemeinsame Anreicherung aller Quellen, nil ohne enrichment
+	severityRules []severityRule // übersetzte Severity-Regeln
 }
 
 type LogEntry struct {
-	Timestamp time.Time
-	Source    string
-	Severity  string
-	Message   string
-	Metadata  map[string]string
+	Timestamp time.Time         `json:"timestamp"`
+	Source    string            `json:"source"`
+	Severity  string            `json:"severity"`
+	Message   string            `json:"message"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
 var levelOrder = map[string]int{
@@ -45,38 +83,13 @@ var levelOrder = map[string]int{
 	"fatal": 4,
 }
 
-var colors = map[string]string{
-	"red":     "#ff5555",
-	"green":   "#50fa7b",
-	"yellow":  "#f1fa8c",
-	"blue":    "#8be9fd",
-	"magenta": "#ff79c6",
-	"cyan":    "#8be9fd",
-	"white":   "#f8f8f2",
-}
-
-var severityColors = map[string]string{
-	"debug": "#6272a4", // grau
-	"info":  "#50fa7b", // grün
-	"warn":  "#f1fa8c", // gelb
-	"error": "#ff5555", // rot
-	"fatal": "#ff79c6", // magenta
-}
-
-// Styles für die UI
+// Styles für die UI, Farben setzt applyTheme
 var (
-	titleStyle = lipgloss.NewStyle().
-	Bold(true).
-	Foreground(lipgloss.Color("#8be9fd")).
-	MarginLeft(2)
-
-	selectedStyle = lipgloss.NewStyle().
-	Bold(true).
-	Foreground(lipgloss.Color("#f8f8f2")).
-	Background(lipgloss.Color("#44475a"))
-
-	helpStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("#6272a4"))
+	titleStyle      lipgloss.Style
+	selectedStyle   lipgloss.Style
+	helpStyle       lipgloss.Style
+	rawLineStyle    lipgloss.Style
+	parseErrorStyle lipgloss.Style
 
 	logLineStyle = lipgloss.NewStyle().
 	MarginLeft(1)
@@ -84,27 +97,89 @@ var (
 
 // List Item für Log-Dateien
 type logFileItem struct {
-	config LogConfig
+	config   LogConfig
+	detected string // automatisch erkannter Typ
+	stats    string // Kurzstatistik aus dem Hintergrund
 }
 
-func (i logFileItem) FilterValue() string { return i.config.Path }
-func (i logFileItem) Title() string       { return i.config.Path }
+func (i logFileItem) FilterValue() string { return i.config.Name() }
+func (i logFileItem) Title() string       { return i.config.Name() }
 func (i logFileItem) Description() string {
-	return fmt.Sprintf("Type: %s | Level: %s | Color: %s", i.config.Type, i.config.LogLevel, i.config.Color)
+	typ := i.config.Type
+	if i.config.autoDetect() {
+		typ = "auto"
+		if i.detected != "" {
+			typ = "auto: " + i.detected
+		}
+	}
+	desc := tr("list.item", typ, i.config.LogLevel, i.config.Color)
+	if i.stats != "" {
+		desc += "\n" + i.stats
+	}
+	return desc
 }
 
+// Bildschirme der Anwendung
+type screen int
+
+const (
+	screenList screen = iota
+	screenLogs
+	screenDiff
+	screenSecurity
+	screenPerformance
+	screenSessions
+)
+
 // Model für die Anwendung
 type model struct {
 	config     *Config
 	list       list.Model
 	viewport   viewport.Model
 	logs       []string
-	showLogs   bool
-	parsers    map[string]Parser
+	screen     screen
 	currentLog LogConfig
+	currentIdx int // Index der Quelle in der Liste
 	keys       keyMap
+
+	view      *ViewConfig // geöffnete Ansicht, nil bei einer einzelnen Quelle
+	naming    bool        // Name für eine neue Ansicht wird eingegeben
+	nameInput textinput.Model
+
+	diffMark   *LogConfig // Basis für den Vergleich zweier Quellen
+	diffSource LogConfig
+	diffWindow int // Index in diffWindows
+
+	security securityState
+	perf     perfState
+	sessions sessionState
+
+	reader     *entryReader
+	entries    []LogEntry
+	entryBase  int // Anzahl bereits verworfener Einträge
+	unparsed   []unparsedLine
+	showRaw    bool
+	levels     levelFilter
+	levelCounts map[string]int // geparste Einträge je Level, auch ausgeblendete
+	tableMode  bool
+	table      tableState
+	notice     string // Hinweis in der Kopfzeile, z.B. nach dem Speichern
+	truncated  bool
+	readErr    error
+	stream     *lineBuffer // laufende Quelle (Kommando oder stdin)
+	streamPos  int
+	streamDone bool
 }
 
+// unparsedLine ist eine Zeile, die der Parser nicht verarbeiten konnte
+type unparsedLine struct {
+	pos  int // Anzahl Einträge (inkl. verworfener) vor dieser Zeile
+	line string
+}
+
+// Maximale Anzahl angezeigter Einträge
+const maxLogEntries = 1000
+
 type keyMap struct {
 	Up     key.Binding
 	Down   key.Binding
@@ -113,6 +188,30 @@ type keyMap struct {
 	Quit   key.Binding
 	Help   key.Binding
 	Reload key.Binding
+	Raw    key.Binding
+	Mark   key.Binding
+	Diff   key.Binding
+	Window key.Binding
+	Security key.Binding
+	Performance key.Binding
+	Activity    key.Binding
+	Search      key.Binding // Benutzer oder IP in der Aktivität eingeben
+
+	// Level
+	Level    key.Binding
+	MinLevel key.Binding
+
+	// Tabellenansicht
+	Table      key.Binding
+	PrevColumn key.Binding
+	NextColumn key.Binding
+	Narrower   key.Binding
+	Wider      key.Binding
+	Sort       key.Binding
+	AddColumn  key.Binding
+	DelColumn  key.Binding
+	Save       key.Binding
+	SaveView   key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
@@ -121,127 +220,241 @@ func (k keyMap) ShortHelp() []key.Binding {
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Up, k.Down, k.Enter},
-		{k.Back, k.Reload, k.Quit},
-	}
-}
-
-var keys = keyMap{
-	Up: key.NewBinding(
-		key.WithKeys("up", "k"),
-			   key.WithHelp("↑/k", "move up"),
-	),
-	Down: key.NewBinding(
-		key.WithKeys("down", "j"),
-			     key.WithHelp("↓/j", "move down"),
-	),
-	Enter: key.NewBinding(
-		key.WithKeys("enter"),
-			      key.WithHelp("enter", "select"),
-	),
-	Back: key.NewBinding(
-		key.WithKeys("esc", "b"),
-			     key.WithHelp("esc", "back"),
-	),
-	Quit: key.NewBinding(
-		key.WithKeys("q", "ctrl+c"),
-			     key.WithHelp("q", "quit"),
-	),
-	Help: key.NewBinding(
-		key.WithKeys("?"),
-			     key.WithHelp("?", "toggle help"),
-	),
-	Reload: key.NewBinding(
-		key.WithKeys("r"),
-			       key.WithHelp("r", "reload"),
-	),
+		{k.Up, k.Down, k.Enter, k.Mark, k.Diff, k.Security, k.Performance, k.Activity},
+		{k.Back, k.Reload, k.Raw, k.Quit},
+		{k.Level, k.MinLevel},
+		{k.Table, k.PrevColumn, k.NextColumn, k.Narrower, k.Wider},
+		{k.Sort, k.AddColumn, k.DelColumn, k.Save, k.SaveView},
+	}
+}
+
+// newKeyMap erstellt die Tastenbelegung mit Hilfetexten in der gewählten Sprache
+func newKeyMap() keyMap {
+	return keyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+				   key.WithHelp("↑/k", tr("key.up")),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+				     key.WithHelp("↓/j", tr("key.down")),
+		),
+		Enter: key.NewBinding(
+			key.WithKeys("enter"),
+				      key.WithHelp("enter", tr("key.enter")),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc", "b"),
+				     key.WithHelp("esc", tr("key.back")),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("q", "ctrl+c"),
+				     key.WithHelp("q", tr("key.quit")),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+				     key.WithHelp("?", tr("key.help")),
+		),
+		Reload: key.NewBinding(
+			key.WithKeys("r"),
+				       key.WithHelp("r", tr("key.reload")),
+		),
+		Raw: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", tr("key.raw")),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", tr("key.mark")),
+		),
+		Diff: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", tr("key.diff")),
+		),
+		Window: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", tr("key.window")),
+		),
+		Level: key.NewBinding(
+			key.WithKeys("1", "2", "3", "4", "5"),
+			key.WithHelp("1-5", tr("key.level")),
+		),
+		MinLevel: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", tr("key.minLevel")),
+		),
+		Security: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", tr("key.security")),
+		),
+		Performance: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", tr("key.performance")),
+		),
+		Activity: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", tr("key.activity")),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", tr("key.search")),
+		),
+		Table: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", tr("key.table")),
+		),
+		PrevColumn: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", tr("key.prevColumn")),
+		),
+		NextColumn: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", tr("key.nextColumn")),
+		),
+		Narrower: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", tr("key.narrower")),
+		),
+		Wider: key.NewBinding(
+			key.WithKeys("+"),
+			key.WithHelp("+", tr("key.wider")),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", tr("key.sort")),
+		),
+		AddColumn: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", tr("key.addColumn")),
+		),
+		DelColumn: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", tr("key.delColumn")),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", tr("key.save")),
+		),
+		SaveView: key.NewBinding(
+			key.WithKeys("V"),
+			key.WithHelp("V", tr("key.saveView")),
+		),
+	}
 }
 
 func initialModel(cfg *Config) model {
 	// Liste der Log-Dateien erstellen
 	items := make([]list.Item, len(cfg.Logs))
 	for i, logCfg := range cfg.Logs {
-		items[i] = logFileItem{config: logCfg}
+		item := logFileItem{config: logCfg}
+		// Dateien können schon vorab erkannt werden
+		if logCfg.autoDetect() && logCfg.Command == "" && !logCfg.isStdin() {
+			if sample, err := sampleFile(logCfg.Path, detectSampleLines); err == nil {
+				item.detected, _ = detectParser(newParsers(logCfg), sample)
+			}
+		}
+		items[i] = item
 	}
+	items = append(items, viewListItems(cfg)...)
 
-	l := list.New(items, list.NewDefaultDelegate(), 80, 20)
-	l.Title = "Log Analyzer - Wähle eine Log-Datei"
+	// Zweite Zeile der Beschreibung für die Statistik
+	delegate := list.NewDefaultDelegate()
+	delegate.SetHeight(3)
+	delegate.Styles = listItemStyles(currentTheme)
+	l := list.New(items, delegate, 80, 20)
+	l.Title = tr("list.title")
+	l.Styles.Title = l.Styles.Title.Foreground(currentTheme.text).Background(currentTheme.selection)
 	l.SetShowStatusBar(false)
 
 	vp := viewport.New(80, 20)
 	vp.Style = lipgloss.NewStyle().
 	BorderStyle(lipgloss.RoundedBorder()).
-	BorderForeground(lipgloss.Color("#44475a")).
+	BorderForeground(currentTheme.border).
 	PaddingLeft(2).
 	PaddingRight(2)
 
-	// Parser Registry
-	parsers := map[string]Parser{
-		"apache":    &ApacheParser{},
-		"nextcloud": &NextcloudParser{},
-	}
-
 	return model{
 		config:   cfg,
 		list:     l,
 		viewport: vp,
-		showLogs: false,
-		parsers:  parsers,
-		keys:     keys,
+		screen:   screenList,
+		diffWindow: 1,
+		keys:     newKeyMap(),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(refreshStats(m.list.Items()), statsTick())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 		case tea.WindowSizeMsg:
-			if !m.showLogs {
-				m.list.SetWidth(msg.Width)
-				m.list.SetHeight(msg.Height - 4)
-			} else {
-				m.viewport.Width = msg.Width - 4
-				m.viewport.Height = msg.Height - 4
-			}
+			m.list.SetWidth(msg.Width)
+			m.list.SetHeight(msg.Height - 4)
+			m.viewport.Width = msg.Width - 4
+			m.viewport.Height = msg.Height - 4
 			return m, nil
 
 		case tea.KeyMsg:
-			if m.showLogs {
-				switch {
-					case key.Matches(msg, m.keys.Back):
-						m.showLogs = false
-						m.logs = nil
-						return m, nil
-					case key.Matches(msg, m.keys.Reload):
-						m.loadLogFile(m.currentLog)
-						return m, nil
-					case key.Matches(msg, m.keys.Quit):
-						return m, tea.Quit
-				}
-				var cmd tea.Cmd
-				m.viewport, cmd = m.viewport.Update(msg)
-				return m, cmd
-			} else {
-				switch {
-					case key.Matches(msg, m.keys.Enter):
-						if item, ok := m.list.SelectedItem().(logFileItem); ok {
-							m.currentLog = item.config
-							m.loadLogFile(item.config)
-							m.showLogs = true
-						}
-						return m, nil
-					case key.Matches(msg, m.keys.Quit):
-						return m, tea.Quit
-				}
-				var cmd tea.Cmd
-				m.list, cmd = m.list.Update(msg)
-				return m, cmd
+			switch m.screen {
+			case screenLogs:
+				return m.updateLogs(msg)
+			case screenDiff:
+				return m.updateDiff(msg)
+			case screenSecurity:
+				return m.updateSecurity(msg)
+			case screenPerformance:
+				return m.updatePerformance(msg)
+			case screenSessions:
+				return m.updateSessions(msg)
+			}
+			return m.updateList(msg)
+
+		case streamMsg:
+			// Nachrichten einer bereits geschlossenen Quelle ignorieren
+			if msg.stream != m.stream {
+				return m, nil
+			}
+			notify := m.readStream()
+			if m.stream == nil {
+				return m, nil
 			}
+			m.renderLogs()
+			if m.streamDone {
+				return m, nil
+			}
+			return m, waitForStream(m.stream, notify)
+
+		case diffMsg:
+			m.showDiff(msg)
+			return m, nil
+
+		case securityMsg:
+			m.showSecurity(msg)
+			return m, nil
+
+		case perfMsg:
+			m.showPerformance(msg)
+			return m, nil
+
+		case sessionMsg:
+			m.showSessions(msg)
+			return m, nil
+
+		case statsMsg:
+			m.showStats(msg)
+			return m, nil
+
+		case statsTickMsg:
+			// Nur aktualisieren, solange die Liste zu sehen ist
+			if m.screen == screenList {
+				return m, tea.Batch(refreshStats(m.list.Items()), statsTick())
+			}
+			return m, statsTick()
 	}
 
-	if !m.showLogs {
+	if m.screen == screenList {
 		var cmd tea.Cmd
 		m.list, cmd = m.list.Update(msg)
 		return m, cmd
@@ -249,48 +462,379 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m *model) loadLogFile(cfg LogConfig) {
-	parser, ok := m.parsers[cfg.Type]
-	if !ok {
-		m.logs = []string{fmt.Sprintf("Fehler: Kein Parser für Typ '%s' gefunden", cfg.Type)}
-		m.viewport.SetContent(strings.Join(m.logs, "\n"))
+func (m model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Während der Filtereingabe gehen alle Tasten an die Liste
+	if m.list.FilterState() == list.Filtering {
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+	}
+
+	switch {
+		case key.Matches(msg, m.keys.Enter):
+			switch item := m.list.SelectedItem().(type) {
+				case logFileItem:
+					m.screen = screenLogs
+					m.currentIdx = m.list.GlobalIndex()
+					m.view = nil
+					return m, m.loadLogFile(item.config)
+				case viewItem:
+					m.screen = screenLogs
+					m.currentIdx = m.list.GlobalIndex()
+					return m, m.openView(item.view)
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Mark):
+			if item, ok := m.list.SelectedItem().(logFileItem); ok {
+				m.toggleMark(item.config)
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Diff):
+			if item, ok := m.list.SelectedItem().(logFileItem); ok {
+				return m, m.startDiff(item.config)
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Security):
+			if item, ok := m.list.SelectedItem().(logFileItem); ok {
+				return m, m.startSecurity(item.config)
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Performance):
+			if item, ok := m.list.SelectedItem().(logFileItem); ok {
+				return m, m.startPerformance(item.config)
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Activity):
+			return m, m.startSessions(nil)
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateLogs(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.naming {
+		return m.updateNaming(msg)
+	}
+
+	switch {
+		case key.Matches(msg, m.keys.Back):
+			m.closeStream()
+			m.screen = screenList
+			m.logs = nil
+			m.entries = nil
+			m.view = nil
+			return m, nil
+		case key.Matches(msg, m.keys.Reload):
+			return m, m.loadLogFile(m.currentLog)
+		case key.Matches(msg, m.keys.Raw):
+			m.showRaw = !m.showRaw
+			m.renderLogs()
+			return m, nil
+		case key.Matches(msg, m.keys.Level):
+			m.levels.toggle(int(msg.String()[0] - '1'))
+			m.refilter()
+			return m, nil
+		case key.Matches(msg, m.keys.MinLevel):
+			m.levels.cycleMin()
+			m.refilter()
+			return m, nil
+		case key.Matches(msg, m.keys.Table):
+			m.tableMode = !m.tableMode
+			m.renderLogs()
+			return m, nil
+		case key.Matches(msg, m.keys.SaveView):
+			return m, m.startNaming()
+		case key.Matches(msg, m.keys.Quit):
+			m.closeStream()
+			return m, tea.Quit
+	}
+
+	if m.tableMode && m.updateTable(msg) {
+		m.renderLogs()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m *model) loadLogFile(cfg LogConfig) tea.Cmd {
+	m.closeStream()
+	m.currentLog = cfg
+	m.table = newTableState(cfg)
+	m.levels = newLevelFilter(cfg)
+	if m.view != nil {
+		for _, level := range m.view.Hide {
+			m.levels.hidden[level] = true
+		}
+	}
+	m.notice = ""
+	if !m.resetEntries() {
+		return nil
+	}
+
+	// Kommandos und stdin laufen weiter und liefern Zeilen nach
+	if cfg.Command != "" || cfg.isStdin() {
+		stream, err := openStream(cfg)
+		if err != nil {
+			m.showError(tr("logs.startError", err))
+			return nil
+		}
+		m.stream = stream
+		m.streamPos = 0
+		m.streamDone = false
+		notify := m.readStream()
+		if m.stream == nil {
+			return nil
+		}
+		m.renderLogs()
+		return waitForStream(stream, notify)
+	}
+	m.readFile()
+	return nil
+}
+
+// resetEntries verwirft die eingelesenen Einträge und erstellt einen neuen
+// Reader für die aktuelle Quelle
+func (m *model) resetEntries() bool {
+	m.entries = nil
+	m.entryBase = 0
+	m.unparsed = nil
+	m.levelCounts = map[string]int{}
+	m.truncated = false
+	m.readErr = nil
+
+	reader, err := newEntryReader(m.currentLog)
+	if err != nil {
+		m.showError(tr("logs.error", err))
+		return false
+	}
+	m.reader = reader
+	return true
+}
+
+// refilter liest die Quelle nach einer Änderung der Level erneut ein. Laufende
+// Quellen werden nicht neu gestartet, sondern aus ihrem Puffer gelesen.
+func (m *model) refilter() {
+	if !m.resetEntries() {
+		return
+	}
+	if m.stream == nil {
+		m.readFile()
 		return
 	}
+	// Auf neue Zeilen wartet bereits das laufende waitForStream
+	m.streamPos = 0
+	m.readStream()
+	if m.stream != nil {
+		m.renderLogs()
+	}
+}
 
-	file, err := os.Open(cfg.Path)
+// readFile liest die aktuelle Quelle als Datei ein
+func (m *model) readFile() {
+	if m.view != nil {
+		m.readView()
+		return
+	}
+	if m.currentLog.indexed() {
+		err := m.readIndexed()
+		if err == nil {
+			m.rememberDetected()
+			m.renderLogs()
+			return
+		}
+		// Ohne Index die Datei direkt lesen
+		if !m.resetEntries() {
+			return
+		}
+		m.notice = tr("index.error", err)
+	}
+
+	file, err := os.Open(m.currentLog.Path)
 	if err != nil {
-		m.logs = []string{fmt.Sprintf("Fehler beim Öffnen der Datei: %v", err)}
-		m.viewport.SetContent(strings.Join(m.logs, "\n"))
+		m.showError(tr("logs.openError", err))
 		return
 	}
 	defer file.Close()
 
-	var logLines []string
-	logLines = append(logLines, titleStyle.Render(fmt.Sprintf("==> %s (%s, Level: %s)", cfg.Path, cfg.Type, cfg.LogLevel)))
-	logLines = append(logLines, "")
-
-	scanner := bufio.NewScanner(file)
-	count := 0
+	scanner := newLineScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		entry, err := parser.Parse(line)
-		if err != nil {
+		m.addParsed(m.reader.add(scanner.Text()))
+	}
+	m.readErr = scanner.Err()
+	m.addParsed(m.reader.idle())
+
+	if m.reader.err != nil {
+		m.showError(tr("logs.error", m.reader.err))
+		return
+	}
+	m.rememberDetected()
+	m.renderLogs()
+}
+
+// addParsed übernimmt die Einträge, die dem Level entsprechen. Bei Dateien
+// werden nach Erreichen der maximalen Anzahl nur noch die Parse-Ergebnisse
+// gezählt.
+func (m *model) addParsed(results []parsedLine) {
+	for _, p := range results {
+		if p.err != nil {
+			if len(m.entries) < maxLogEntries || m.stream != nil {
+				m.unparsed = append(m.unparsed, unparsedLine{pos: m.entryBase + len(m.entries), line: p.line})
+				if len(m.unparsed) > maxLogEntries {
+					m.unparsed = m.unparsed[1:]
+				}
+			}
 			continue
 		}
 
-		if !shouldLog(cfg.LogLevel, entry.Severity) {
+		m.levelCounts[p.entry.Severity]++
+		if !m.levels.shows(p.entry.Severity) {
 			continue
 		}
 
+		if len(m.entries) >= maxLogEntries {
+			m.truncated = true
+			if m.stream == nil {
+				continue
+			}
+			// Bei laufenden Quellen die ältesten Einträge verwerfen
+			m.entries = m.entries[1:]
+			m.entryBase++
+			for len(m.unparsed) > 0 && m.unparsed[0].pos < m.entryBase {
+				m.unparsed = m.unparsed[1:]
+			}
+		}
+		m.entries = append(m.entries, p.entry)
+	}
+}
+
+// readStream übernimmt alle neuen Zeilen der laufenden Quelle
+func (m *model) readStream() <-chan struct{} {
+	lines, next, done, err, notify := m.stream.since(m.streamPos)
+	for _, line := range lines {
+		m.addParsed(m.reader.add(line))
+	}
+	// Alle vorhandenen Zeilen sind gelesen: Zurückgehaltenes anzeigen, auch
+	// wenn die Quelle noch läuft
+	m.addParsed(m.reader.idle())
+	m.streamPos = next
+	m.streamDone = done
+	m.readErr = err
+
+	if m.reader.err != nil {
+		m.closeStream()
+		m.showError(tr("logs.error", m.reader.err))
+		return notify
+	}
+	m.rememberDetected()
+	return notify
+}
+
+// rememberDetected übernimmt einen automatisch erkannten Typ in die Liste
+func (m *model) rememberDetected() {
+	if !m.currentLog.autoDetect() || m.reader.typ == "" {
+		return
+	}
+	items := m.list.Items()
+	if m.currentIdx < len(items) {
+		if item, ok := items[m.currentIdx].(logFileItem); ok && item.config.Name() == m.currentLog.Name() && item.detected != m.reader.typ {
+			item.detected = m.reader.typ
+			m.list.SetItem(m.currentIdx, item)
+		}
+	}
+}
+
+// showError zeigt statt der Einträge nur eine Fehlermeldung an
+func (m *model) showError(msg string) {
+	m.logs = []string{msg}
+	m.viewport.SetContent(msg)
+}
+
+// closeStream beendet die laufende Quelle der Ansicht
+func (m *model) closeStream() {
+	if m.stream != nil {
+		m.stream.Close()
+		m.stream = nil
+	}
+}
+
+// streamMsg signalisiert neue Zeilen einer laufenden Quelle
+type streamMsg struct {
+	stream *lineBuffer
+}
+
+// Wartezeit, um schnell eintreffende Zeilen gesammelt darzustellen
+const streamBatchDelay = 100 * time.Millisecond
+
+func waitForStream(stream *lineBuffer, notify <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		<-notify
+		time.Sleep(streamBatchDelay)
+		return streamMsg{stream: stream}
+	}
+}
+
+func (m *model) renderLogs() {
+	cfg := m.currentLog
+	follow := m.viewport.AtBottom()
+
+	var logLines []string
+	typ := m.reader.typ
+	if cfg.autoDetect() {
+		if typ == "" {
+			typ = "..."
+		}
+		typ = "auto: " + typ
+	}
+	header := tr("logs.header", cfg.Name(), typ, m.levels.min)
+	if m.view != nil {
+		header = tr("logs.viewHeader", m.view.Name, len(m.view.sources(m.config)), m.levels.min)
+	}
+	logLines = append(logLines, titleStyle.Render(header))
+	logLines = append(logLines, m.parseSummary())
+	logLines = append(logLines, m.levels.renderLevelCounts(m.levelCounts))
+	if m.notice != "" {
+		logLines = append(logLines, helpStyle.Render(m.notice))
+	}
+	logLines = append(logLines, "")
+
+	// Laufende Quellen und Ansichten behalten die neuesten Einträge
+	if m.truncated && (m.stream != nil || m.view != nil) {
+		logLines = append(logLines, helpStyle.Render(tr("logs.dropped", maxLogEntries)))
+	}
+
+	if m.tableMode {
+		logLines = append(logLines, m.renderTable()...)
+	}
+
+	raw := 0
+	for i, entry := range m.entries {
+		if m.tableMode {
+			break
+		}
+
+		// Nicht parsebare Zeilen an ihrer Position einfügen
+		for m.showRaw && raw < len(m.unparsed) && m.unparsed[raw].pos <= m.entryBase+i {
+			logLines = append(logLines, m.renderUnparsed(m.unparsed[raw]))
+			raw++
+		}
+
+		entry = m.config.redact(entry)
+
 		// Styling der Log-Zeile
-		ts := entry.Timestamp.Format("02.01.2006 15:04")
+		ts := m.config.displayTime(entry.Timestamp).Format("02.01.2006 15:04")
 
 		sourceStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color(colors[cfg.Color])).
+		Foreground(sourceColor(cfg.Color)).
 		Bold(true)
 
 		severityStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color(severityColors[entry.Severity])).
+		Foreground(severityColor(entry.Severity)).
 		Bold(true)
 
 		logLine := fmt.Sprintf("[%s] %s | %s | %s",
@@ -301,31 +845,105 @@ func (m *model) loadLogFile(cfg LogConfig) {
 		)
 
 		logLines = append(logLines, logLineStyle.Render(logLine))
-		count++
+	}
 
-		// Begrenzen auf 1000 Zeilen für Performance
-		if count >= 1000 {
-			logLines = append(logLines, "")
-			logLines = append(logLines, helpStyle.Render("... (weitere Einträge wurden abgeschnitten, maximal 1000 Zeilen angezeigt)"))
-			break
-		}
+	for m.showRaw && !m.tableMode && raw < len(m.unparsed) {
+		logLines = append(logLines, m.renderUnparsed(m.unparsed[raw]))
+		raw++
 	}
 
-	if err := scanner.Err(); err != nil {
-		logLines = append(logLines, fmt.Sprintf("Fehler beim Lesen: %v", err))
+	// Begrenzen auf 1000 Zeilen für Performance
+	if m.truncated && m.stream == nil && m.view == nil {
+		logLines = append(logLines, "")
+		logLines = append(logLines, helpStyle.Render(tr("logs.truncated", maxLogEntries)))
 	}
 
-	if count == 0 {
-		logLines = append(logLines, helpStyle.Render("Keine Log-Einträge gefunden oder alle wurden gefiltert."))
+	if m.readErr != nil {
+		logLines = append(logLines, tr("logs.readError", m.readErr))
+	}
+
+	if len(m.entries) == 0 && !(m.showRaw && len(m.unparsed) > 0) {
+		logLines = append(logLines, helpStyle.Render(tr("logs.empty")))
+	}
+
+	if m.stream != nil && m.streamDone {
+		logLines = append(logLines, helpStyle.Render(tr("logs.streamEnded")))
 	}
 
 	m.logs = logLines
 	m.viewport.SetContent(strings.Join(logLines, "\n"))
+	if m.stream != nil && follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+// parseSummary zeigt, wie viele Zeilen der Parser verarbeiten konnte
+func (m *model) parseSummary() string {
+	parsed, failed := m.reader.parsed, m.reader.failed
+	total := parsed + failed
+	summary := helpStyle.Render(tr("logs.summary", total, parsed))
+	if failed == 0 {
+		return summary
+	}
+
+	ratio := float64(failed) / float64(total) * 100
+	failedInfo := parseErrorStyle.Render(tr("logs.unparsed", failed, ratio))
+	summary = fmt.Sprintf("%s %s %s", summary, helpStyle.Render("|"), failedInfo)
+	if ratio >= 50 {
+		summary += parseErrorStyle.Render(tr("logs.wrongParser"))
+	}
+	return summary
+}
+
+func (m *model) renderUnparsed(u unparsedLine) string {
+	return logLineStyle.Render(rawLineStyle.Render("✗ " + m.config.redactText(u.line)))
 }
 
 func (m model) View() string {
-	if !m.showLogs {
-		help := helpStyle.Render("Pfeiltasten: Navigation | Enter: Auswählen | q: Beenden | ?: Hilfe")
+	if m.screen == screenSecurity {
+		help := helpStyle.Render(tr("security.help"))
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.viewport.View(),
+			help,
+		)
+	}
+
+	if m.screen == screenSessions {
+		help := helpStyle.Render(tr("sessions.help"))
+		if m.sessions.actor == nil {
+			help = helpStyle.Render(tr("sessions.chooseHelp"))
+		}
+		if m.sessions.entering {
+			help = m.sessions.input.View()
+		}
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.viewport.View(),
+			help,
+		)
+	}
+
+	if m.screen == screenPerformance {
+		help := helpStyle.Render(tr("perf.help"))
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.viewport.View(),
+			help,
+		)
+	}
+
+	if m.screen == screenDiff {
+		help := helpStyle.Render(tr("diff.help"))
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.viewport.View(),
+			help,
+		)
+	}
+
+	if m.screen == screenList {
+		help := helpStyle.Render(tr("list.help"))
 		return lipgloss.JoinVertical(
 			lipgloss.Left,
 			m.list.View(),
@@ -333,7 +951,13 @@ func (m model) View() string {
 		)
 	}
 
-	help := helpStyle.Render("Pfeiltasten: Scrollen | r: Neu laden | Esc: Zurück | q: Beenden")
+	help := helpStyle.Render(tr("logs.help"))
+	if m.tableMode {
+		help = helpStyle.Render(tr("table.help"))
+	}
+	if m.naming {
+		help = m.nameInput.View()
+	}
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.viewport.View(),
@@ -341,23 +965,93 @@ func (m model) View() string {
 	)
 }
 
-// Parser Interface und Implementierungen (unverändert)
+// Parser Interface und Implementierungen
 type Parser interface {
 	Parse(line string) (LogEntry, error)
 }
 
-type ApacheParser struct{}
+// newParsers erstellt alle Parser, konfiguriert für die jeweilige Quelle
+func newParsers(cfg LogConfig) map[string]Parser {
+	ts := newTimeParser(cfg)
+	parsers := map[string]Parser{
+		"apache":       &ApacheParser{time: ts},
+		"apache_error": &ApacheErrorParser{time: ts},
+		"nginx_error":  &NginxErrorParser{time: ts},
+		"nextcloud":    &NextcloudParser{time: ts},
+		"nginx":        newNginxParser(cfg, ts),
+		"docker":       &DockerParser{newContainerParser(cfg, "docker")},
+		"cri":          &CRIParser{newContainerParser(cfg, "cri")},
+	}
+	if cfg.plugin != nil {
+		parsers[cfg.plugin.Name] = &PluginParser{cfg: *cfg.plugin}
+	}
+	return parsers
+}
+
+// Apache Access-Log im Common- oder Combined-Format
+// mit optional angehängtem %D (Antwortzeit in Mikrosekunden)
+var apacheAccessRegex = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "([^"]*)" (\d{3}) (\S+)(?: "([^"]*)" "([^"]*)"(?: (\d+)$)?)?`)
+
+type ApacheParser struct {
+	time timeParser
+}
 
 func (p *ApacheParser) Parse(line string) (LogEntry, error) {
+	match := apacheAccessRegex.FindStringSubmatch(line)
+	if match == nil {
+		return LogEntry{}, errorf("parser.apache")
+	}
+
+	t, err := p.time.parse(match[4], "02/Jan/2006:15:04:05 -0700")
+	if err != nil {
+		return LogEntry{}, err
+	}
+
+	metadata := map[string]string{
+		"remoteAddr": match[1],
+		"user":       emptyDash(match[3]),
+		"status":     match[6],
+		"bytes":      emptyDash(match[7]),
+		"referer":    emptyDash(match[8]),
+		"userAgent":  match[9],
+	}
+	if parts := strings.Fields(match[5]); len(parts) == 3 {
+		metadata["method"] = parts[0]
+		metadata["url"] = parts[1]
+		metadata["protocol"] = parts[2]
+	}
+	if us, err := strconv.ParseFloat(match[10], 64); err == nil {
+		metadata["requestTime"] = strconv.FormatFloat(us/1e6, 'f', -1, 64)
+	}
+
 	return LogEntry{
-		Timestamp: time.Now(),
+		Timestamp: t,
 		Source:    "apache",
-		Severity:  "info",
-		Message:   line,
-		Metadata:  map[string]string{},
+		Severity:  statusSeverity(match[6]),
+		Message:   fmt.Sprintf("%s %s", match[6], match[5]),
+		Metadata:  metadata,
 	}, nil
 }
 
+// emptyDash ersetzt den Platzhalter "-" durch einen leeren Wert
+func emptyDash(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// statusSeverity leitet das Level aus dem HTTP-Statuscode ab
+func statusSeverity(status string) string {
+	switch {
+	case strings.HasPrefix(status, "5"):
+		return "error"
+	case strings.HasPrefix(status, "4"):
+		return "warn"
+	}
+	return "info"
+}
+
 type NextcloudLog struct {
 	ReqID      string                 `json:"reqId"`
 	Level      int                    `json:"level"`
@@ -373,7 +1067,9 @@ type NextcloudLog struct {
 	Data       map[string]interface{} `json:"data"`
 }
 
-type NextcloudParser struct{}
+type NextcloudParser struct {
+	time timeParser
+}
 
 func (p *NextcloudParser) Parse(line string) (LogEntry, error) {
 	var nc NextcloudLog
@@ -381,9 +1077,9 @@ func (p *NextcloudParser) Parse(line string) (LogEntry, error) {
 		return LogEntry{}, err
 	}
 
-	t, err := time.Parse(time.RFC3339, nc.Time)
+	t, err := p.time.parse(nc.Time, time.RFC3339)
 	if err != nil {
-		t = time.Now()
+		return LogEntry{}, err
 	}
 
 	severity := map[int]string{
@@ -404,6 +1100,9 @@ func (p *NextcloudParser) Parse(line string) (LogEntry, error) {
 			"remoteAddr": nc.RemoteAddr,
 			"user":       nc.User,
 			"app":        nc.App,
+			"method":     nc.Method,
+			"url":        nc.URL,
+			"userAgent":  nc.UserAgent,
 		},
 	}, nil
 }
@@ -422,14 +1121,106 @@ func loadConfig(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	cfg.path = path
+
+	if err := setLanguage(cfg.Language); err != nil {
+		return nil, err
+	}
+	if cfg.displayLoc, err = loadLocation(cfg.DisplayTimezone); err != nil {
+		return nil, errorf("config.displayTimezone", cfg.DisplayTimezone, err)
+	}
+	if cfg.redactor, err = newRedactor(cfg.Redaction); err != nil {
+		return nil, err
+	}
+	if _, ok := themes[cfg.Theme]; !ok {
+		return nil, errorf("config.theme", cfg.Theme)
+	}
+	if cfg.enricher, err = newEnricher(cfg.Enrichment); err != nil {
+		return nil, err
+	}
+	plugins := map[string]*PluginConfig{}
+	for i, plugin := range cfg.Plugins {
+		if _, ok := newParsers(LogConfig{})[plugin.Name]; ok || plugin.Name == "" || plugin.Name == "auto" || plugins[plugin.Name] != nil {
+			return nil, errorf("config.pluginName", plugin.Name)
+		}
+		if plugin.Command == "" {
+			return nil, errorf("config.pluginCommand", plugin.Name)
+		}
+		plugins[plugin.Name] = &cfg.Plugins[i]
+	}
+	for i, logCfg := range cfg.Logs {
+		cfg.Logs[i].index = cfg.Index
+		cfg.Logs[i].plugin = plugins[logCfg.Type]
+		cfg.Logs[i].enricher = cfg.enricher
+		if _, err := loadLocation(logCfg.Timezone); err != nil {
+			return nil, errorf("config.timezone", logCfg.Timezone, logCfg.Name(), err)
+		}
+		if _, err := phpDateLayout(logCfg.LogDateFormat); err != nil {
+			return nil, errorf("config.logDateFormat", logCfg.Name(), err)
+		}
+		if logCfg.LogFormat != "" {
+			if _, err := compileLogFormat(logCfg.LogFormat); err != nil {
+				return nil, errorf("config.logFormat", logCfg.Name(), err)
+			}
+		}
+		if _, ok := parseColor(logCfg.Color); !ok {
+			return nil, errorf("config.color", logCfg.Color, logCfg.Name())
+		}
+		if cfg.Logs[i].severityRules, err = compileSeverityRules(logCfg.Severity); err != nil {
+			return nil, errorf("config.severity", logCfg.Name(), err)
+		}
+	}
+	views := map[string]bool{}
+	for _, v := range cfg.Views {
+		if v.Name == "" || views[v.Name] {
+			return nil, errorf("config.viewName", v.Name)
+		}
+		views[v.Name] = true
+		if err := v.validate(&cfg); err != nil {
+			return nil, err
+		}
+	}
 	return &cfg, nil
 }
 
 func main() {
+	// Bis die config.yaml gelesen ist, gilt die Sprache der Umgebung
+	setLanguage("")
 	cfg, err := loadConfig("config.yaml")
 	if err != nil {
 		log.Fatal(err)
 	}
+	applyTheme(themes[cfg.Theme]())
+
+	// Ohne Argument startet die TUI, sonst der gewählte Modus
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "serve":
+			err = runServe(cfg, os.Args[2:])
+		case "metrics":
+			err = runMetrics(cfg, os.Args[2:])
+		case "forward":
+			err = runForward(cfg, os.Args[2:])
+		case "diff":
+			err = runDiff(cfg, os.Args[2:])
+		case "security":
+			err = runSecurity(cfg, os.Args[2:])
+		case "query":
+			err = runQuery(cfg, os.Args[2:])
+		case "perf":
+			err = runPerformance(cfg, os.Args[2:])
+		case "sessions":
+			err = runSessions(cfg, os.Args[2:])
+		default:
+			fmt.Println(tr("usage"))
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	p := tea.NewProgram(
 		initialModel(cfg),
@@ -438,7 +1229,7 @@ func main() {
 	)
 
 	if _, err := p.Run(); err != nil {
-		fmt.Printf("Fehler beim Starten der Anwendung: %v", err)
+		fmt.Println(tr("app.startError", err))
 		os.Exit(1)
 	}
 }
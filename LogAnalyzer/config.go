@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sourceIndex liefert den Index einer Quelle in der Konfiguration
+func (c *Config) sourceIndex(src LogConfig) int {
+	for i, logCfg := range c.Logs {
+		if logCfg.Name() == src.Name() {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveSourceField setzt ein Feld einer Quelle direkt in der config.yaml.
+// Die Datei wird als YAML-Baum bearbeitet, damit Kommentare erhalten bleiben.
+func (c *Config) saveSourceField(index int, field string, value interface{}) error {
+	return c.editConfigFile(func(root *yaml.Node) error {
+		logs := mappingValue(root, "logs")
+		if logs == nil || logs.Kind != yaml.SequenceNode || index >= len(logs.Content) {
+			return errorf("config.sourceMissing", index, c.path)
+		}
+		return setMappingValue(logs.Content[index], field, value)
+	})
+}
+
+// editConfigFile liest die config.yaml als YAML-Baum, wendet edit an und
+// schreibt die Datei zurück
+func (c *Config) editConfigFile(edit func(root *yaml.Node) error) error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return errorf("config.layout", c.path)
+	}
+	if err := edit(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	enc.Close()
+	return os.WriteFile(c.path, buf.Bytes(), 0644)
+}
+
+// mappingValue liefert den Wert zu key in einem YAML-Mapping
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue setzt key in einem YAML-Mapping. Listen von Objekten werden
+// kompakt in einer Zeile je Element geschrieben.
+func setMappingValue(mapping *yaml.Node, key string, value interface{}) error {
+	if mapping.Kind != yaml.MappingNode {
+		return errorf("config.cannotSet", key)
+	}
+
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return err
+	}
+	if node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			if item.Kind == yaml.MappingNode {
+				item.Style = yaml.FlowStyle
+			}
+		}
+	}
+
+	if existing := mappingValue(mapping, key); existing != nil {
+		*existing = node
+		return nil
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&node,
+	)
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// errPartialLine meldet eine Teilzeile, die erst mit den folgenden Zeilen einen
+// Eintrag ergibt. Sie zählt weder als geparst noch als Fehler.
+var errPartialLine = errors.New("partial line")
+
+// containerLine ist eine Zeile der Container-Laufzeit mit Zeit, Stream und
+// der eigentlichen Ausgabe der Anwendung
+type containerLine struct {
+	time   time.Time
+	stream string
+	text   string
+}
+
+// containerParser setzt Teilzeilen zusammen und übergibt die Ausgabe der
+// Anwendung an den passenden vorhandenen Parser
+type containerParser struct {
+	cfg     LogConfig
+	source  string
+	inner   []Parser
+	last    Parser                    // zuletzt passender Parser, wird zuerst probiert
+	partial map[string]*containerLine // bisher gesammelte Teilzeilen je Stream
+}
+
+func newContainerParser(cfg LogConfig, source string) *containerParser {
+	return &containerParser{cfg: cfg, source: source, partial: map[string]*containerLine{}}
+}
+
+// innerParsers erstellt die Parser für die Ausgabe erst bei Bedarf, da
+// newParsers selbst die Container-Parser enthält
+func (p *containerParser) innerParsers() []Parser {
+	if p.inner == nil {
+		parsers := newParsers(p.cfg)
+		names := make([]string, 0, len(parsers))
+		for name := range parsers {
+			if name != "docker" && name != "cri" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		p.inner = []Parser{}
+		for _, name := range names {
+			p.inner = append(p.inner, parsers[name])
+		}
+	}
+	return p.inner
+}
+
+// assemble sammelt Teilzeilen eines Streams. Erst mit der letzten Teilzeile
+// wird die vollständige Zeile geliefert.
+func (p *containerParser) assemble(l containerLine, complete bool) (containerLine, bool) {
+	if prev := p.partial[l.stream]; prev != nil {
+		l.text = prev.text + l.text
+	}
+	if !complete {
+		p.partial[l.stream] = &l
+		return l, false
+	}
+	delete(p.partial, l.stream)
+	return l, true
+}
+
+func (p *containerParser) hasPartial() bool {
+	return len(p.partial) > 0
+}
+
+// flushPartial liefert die bisher gesammelten Teilzeilen als Einträge, z.B.
+// wenn eine laufende Quelle gerade keine weiteren Zeilen schreibt
+func (p *containerParser) flushPartial() []LogEntry {
+	streams := make([]string, 0, len(p.partial))
+	for stream := range p.partial {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	entries := make([]LogEntry, 0, len(streams))
+	for _, stream := range streams {
+		l := *p.partial[stream]
+		l.text = strings.TrimRight(l.text, "\r\n")
+		entries = append(entries, p.entry(l))
+		delete(p.partial, stream)
+	}
+	return entries
+}
+
+// entry wertet die Ausgabe der Anwendung aus: mit einem passenden Parser,
+// als JSON-Objekt oder als einfacher Text
+func (p *containerParser) entry(l containerLine) LogEntry {
+	parsers := p.innerParsers()
+	if p.last != nil {
+		parsers = append([]Parser{p.last}, parsers...)
+	}
+	for _, parser := range parsers {
+		// JSON ohne Meldung passt nur zufällig, z.B. zu den Feldern von Nextcloud
+		if e, err := parser.Parse(l.text); err == nil && e.Message != "" {
+			p.last = parser
+			if e.Metadata == nil {
+				e.Metadata = map[string]string{}
+			}
+			e.Metadata["stream"] = l.stream
+			return e
+		}
+	}
+
+	e := LogEntry{
+		Timestamp: l.time,
+		Source:    p.source,
+		Severity:  "info",
+		Message:   l.text,
+		Metadata:  map[string]string{"stream": l.stream},
+	}
+	if l.stream == "stderr" {
+		e.Severity = "error"
+	}
+	jsonPayload(&e)
+	return e
+}
+
+// jsonPayload übernimmt Meldung, Level und weitere Felder aus einer Ausgabe
+// im JSON-Format, z.B. von strukturierten Loggern
+func jsonPayload(e *LogEntry) {
+	if !strings.HasPrefix(e.Message, "{") {
+		return
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(e.Message), &fields); err != nil {
+		return
+	}
+	for key, value := range fields {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "msg", "message":
+			e.Message = s
+		case "level", "severity", "lvl":
+			level := strings.ToLower(s)
+			if level == "warning" {
+				level = "warn"
+			}
+			if _, ok := levelOrder[level]; ok {
+				e.Severity = level
+			}
+		default:
+			e.Metadata[key] = s
+		}
+	}
+}
+
+// dockerLine ist eine Zeile des json-file Treibers von Docker
+type dockerLine struct {
+	Log    string `json:"log"`
+	Stream string `json:"stream"`
+	Time   string `json:"time"`
+}
+
+// DockerParser liest Logs des json-file Treibers. Lange Ausgaben teilt Docker
+// in mehrere Zeilen ohne abschließenden Zeilenumbruch.
+type DockerParser struct {
+	*containerParser
+}
+
+func (p *DockerParser) Parse(line string) (LogEntry, error) {
+	var d dockerLine
+	if err := json.Unmarshal([]byte(line), &d); err != nil {
+		return LogEntry{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, d.Time)
+	if err != nil || d.Stream == "" {
+		return LogEntry{}, errorf("parser.docker")
+	}
+
+	l, complete := p.assemble(containerLine{time: t, stream: d.Stream, text: d.Log}, strings.HasSuffix(d.Log, "\n"))
+	if !complete {
+		return LogEntry{}, errPartialLine
+	}
+	l.text = strings.TrimRight(l.text, "\r\n")
+	return p.entry(l), nil
+}
+
+// CRIParser liest Logs von containerd und CRI-O, wie sie Kubernetes unter
+// /var/log/pods ablegt: "<Zeit> <Stream> <P|F> <Ausgabe>"
+type CRIParser struct {
+	*containerParser
+}
+
+func (p *CRIParser) Parse(line string) (LogEntry, error) {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 || (parts[1] != "stdout" && parts[1] != "stderr") || (parts[2] != "P" && parts[2] != "F") {
+		return LogEntry{}, errorf("parser.cri")
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return LogEntry{}, err
+	}
+	text := ""
+	if len(parts) == 4 {
+		text = parts[3]
+	}
+
+	l, complete := p.assemble(containerLine{time: t, stream: parts[1], text: text}, parts[2] == "F")
+	if !complete {
+		return LogEntry{}, errPartialLine
+	}
+	return p.entry(l), nil
+}
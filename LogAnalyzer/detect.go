@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Anzahl Zeilen, die für die Erkennung des Formats geprüft werden
+const detectSampleLines = 50
+
+// autoDetect prüft, ob das Format der Quelle automatisch erkannt werden soll
+func (c LogConfig) autoDetect() bool {
+	return c.Type == "" || c.Type == "auto"
+}
+
+// detectParser probiert alle Parser an den Beispielzeilen aus und liefert den
+// Typ mit der höchsten Erfolgsquote. Bei Gleichstand gewinnt der alphabetisch
+// erste Typ, damit das Ergebnis stabil bleibt. Plugins sind nur enthalten,
+// wenn die Quelle eines über type auswählt.
+func detectParser(parsers map[string]Parser, lines []string) (string, float64) {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bestType, bestRate := "", 0.0
+	for _, name := range names {
+		total, ok := 0, 0
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			total++
+			if _, err := parsers[name].Parse(line); err == nil || err == errPartialLine {
+				ok++
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		if rate := float64(ok) / float64(total); rate > bestRate {
+			bestType, bestRate = name, rate
+		}
+	}
+	return bestType, bestRate
+}
+
+// sampleFile liest die ersten n Zeilen einer Datei
+func sampleFile(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := newLineScanner(file)
+	for len(lines) < n && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
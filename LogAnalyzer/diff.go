@@ -0,0 +1,334 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Platzhalter für variable Teile einer Meldung, damit gleiche Meldungen mit
+// unterschiedlichen IDs, Adressen oder Zahlen zusammengefasst werden
+var messageNormalizers = []struct {
+	re          *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`), "<email>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`), "<ip>"},
+	{regexp.MustCompile(`\b[0-9a-f]{8,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<n>"},
+}
+
+// normalizeMessage ersetzt variable Teile einer Meldung durch Platzhalter
+func normalizeMessage(msg string) string {
+	for _, n := range messageNormalizers {
+		msg = n.re.ReplaceAllString(msg, n.placeholder)
+	}
+	return strings.TrimSpace(msg)
+}
+
+// diffGroup ist eine Gruppe gleicher (normalisierter) Meldungen
+type diffGroup struct {
+	Message  string
+	Severity string
+	Before   int
+	After    int
+}
+
+// diffResult enthält die Unterschiede zwischen zwei Mengen von Einträgen
+type diffResult struct {
+	Before    string // Beschreibung der ersten Menge
+	After     string // Beschreibung der zweiten Menge
+	New       []diffGroup
+	Gone      []diffGroup
+	Increased []diffGroup
+	Decreased []diffGroup
+}
+
+// Ab diesem Faktor gilt eine Meldung als deutlich häufiger bzw. seltener
+const diffFactor = 2.0
+
+// diffEntries gruppiert beide Mengen nach normalisierter Meldung und ordnet die
+// Gruppen als neu, verschwunden, häufiger oder seltener ein
+func diffEntries(before, after []LogEntry) diffResult {
+	groups := map[string]*diffGroup{}
+	count := func(entries []LogEntry, after bool) {
+		for _, e := range entries {
+			msg := normalizeMessage(e.Message)
+			g, ok := groups[msg]
+			if !ok {
+				g = &diffGroup{Message: msg, Severity: e.Severity}
+				groups[msg] = g
+			}
+			// Das höchste Level der Gruppe anzeigen
+			if levelOrder[e.Severity] > levelOrder[g.Severity] {
+				g.Severity = e.Severity
+			}
+			if after {
+				g.After++
+			} else {
+				g.Before++
+			}
+		}
+	}
+	count(before, false)
+	count(after, true)
+
+	var result diffResult
+	for _, g := range groups {
+		switch {
+		case g.Before == 0:
+			result.New = append(result.New, *g)
+		case g.After == 0:
+			result.Gone = append(result.Gone, *g)
+		case float64(g.After) >= float64(g.Before)*diffFactor:
+			result.Increased = append(result.Increased, *g)
+		case float64(g.Before) >= float64(g.After)*diffFactor:
+			result.Decreased = append(result.Decreased, *g)
+		}
+	}
+
+	// Größte Veränderung zuerst
+	for _, groups := range [][]diffGroup{result.New, result.Gone, result.Increased, result.Decreased} {
+		sort.Slice(groups, func(i, j int) bool {
+			di, dj := abs(groups[i].After-groups[i].Before), abs(groups[j].After-groups[j].Before)
+			if di != dj {
+				return di > dj
+			}
+			return groups[i].Message < groups[j].Message
+		})
+	}
+	return result
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// redact ersetzt personenbezogene Daten, die nach dem Normalisieren noch in
+// den Meldungen stehen
+func (r *diffResult) redact(cfg *Config) {
+	for _, groups := range [][]diffGroup{r.New, r.Gone, r.Increased, r.Decreased} {
+		for i := range groups {
+			groups[i].Message = cfg.redactText(groups[i].Message)
+		}
+	}
+}
+
+// splitWindows teilt die Einträge in die letzten beiden Zeitfenster vor dem
+// jüngsten Eintrag: (end-2w, end-w] und (end-w, end]
+func splitWindows(entries []LogEntry, window time.Duration) (before, after []LogEntry, end time.Time) {
+	for _, e := range entries {
+		if e.Timestamp.After(end) {
+			end = e.Timestamp
+		}
+	}
+	for _, e := range entries {
+		switch {
+		case e.Timestamp.After(end.Add(-window)):
+			after = append(after, e)
+		case e.Timestamp.After(end.Add(-2 * window)):
+			before = append(before, e)
+		}
+	}
+	return before, after, end
+}
+
+// readDiffSource liest eine Quelle für den Vergleich. Kommandos wie
+// "docker logs -f" und stdin enden nicht und werden abgelehnt.
+func readDiffSource(src LogConfig) ([]LogEntry, error) {
+	if src.Command != "" || src.isStdin() {
+		return nil, errorf("diff.stream", src.Name())
+	}
+	entries, _, err := readEntries(src)
+	return entries, err
+}
+
+// compareWindows vergleicht die letzten beiden Zeitfenster einer Quelle
+func compareWindows(cfg *Config, src LogConfig, window time.Duration) (diffResult, error) {
+	entries, err := readDiffSource(src)
+	if err != nil {
+		return diffResult{}, err
+	}
+	before, after, end := splitWindows(entries, window)
+
+	result := diffEntries(before, after)
+	result.redact(cfg)
+	format := "02.01.2006 15:04"
+	result.Before = fmt.Sprintf("%s %s - %s", src.Name(),
+		cfg.displayTime(end.Add(-2*window)).Format(format), cfg.displayTime(end.Add(-window)).Format(format))
+	result.After = fmt.Sprintf("%s %s - %s", src.Name(),
+		cfg.displayTime(end.Add(-window)).Format(format), cfg.displayTime(end).Format(format))
+	return result, nil
+}
+
+// compareSources vergleicht zwei Quellen vollständig
+func compareSources(cfg *Config, a, b LogConfig) (diffResult, error) {
+	before, err := readDiffSource(a)
+	if err != nil {
+		return diffResult{}, err
+	}
+	after, err := readDiffSource(b)
+	if err != nil {
+		return diffResult{}, err
+	}
+
+	result := diffEntries(before, after)
+	result.redact(cfg)
+	result.Before = a.Name()
+	result.After = b.Name()
+	return result, nil
+}
+
+// Zeitfenster, die im Vergleich durchgeschaltet werden können
+var diffWindows = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+// diffMsg liefert das Ergebnis eines Vergleichs an die TUI
+type diffMsg struct {
+	result diffResult
+	err    error
+}
+
+// toggleMark merkt sich eine Quelle als Basis für den Vergleich zweier Dateien
+func (m *model) toggleMark(cfg LogConfig) {
+	if m.diffMark != nil && m.diffMark.Name() == cfg.Name() {
+		m.diffMark = nil
+		m.list.NewStatusMessage(tr("diff.unmarked"))
+		return
+	}
+	m.diffMark = &cfg
+	m.list.NewStatusMessage(tr("diff.marked", cfg.Name()))
+}
+
+// startDiff vergleicht die markierte mit der gewählten Quelle oder, ohne
+// Markierung, die letzten beiden Zeitfenster der gewählten Quelle
+func (m *model) startDiff(cfg LogConfig) tea.Cmd {
+	m.screen = screenDiff
+	m.diffSource = cfg
+	m.showError(tr("diff.running"))
+
+	config := m.config
+	window := diffWindows[m.diffWindow]
+	if m.diffMark != nil && m.diffMark.Name() != cfg.Name() {
+		mark := *m.diffMark
+		return func() tea.Msg {
+			result, err := compareSources(config, mark, cfg)
+			return diffMsg{result: result, err: err}
+		}
+	}
+	return func() tea.Msg {
+		result, err := compareWindows(config, cfg, window)
+		return diffMsg{result: result, err: err}
+	}
+}
+
+func (m model) updateDiff(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.screen = screenList
+		return m, nil
+	case key.Matches(msg, m.keys.Window):
+		m.diffWindow = (m.diffWindow + 1) % len(diffWindows)
+		// Das Zeitfenster gilt nur für den Vergleich innerhalb einer Quelle
+		m.diffMark = nil
+		return m, m.startDiff(m.diffSource)
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// showDiff stellt das Ergebnis eines Vergleichs dar
+func (m *model) showDiff(msg diffMsg) {
+	if m.screen != screenDiff {
+		return
+	}
+	if msg.err != nil {
+		m.showError(tr("diff.error", msg.err))
+		return
+	}
+	m.logs = renderDiff(msg.result)
+	m.viewport.SetContent(strings.Join(m.logs, "\n"))
+	m.viewport.GotoTop()
+}
+
+func renderDiff(r diffResult) []string {
+	lines := []string{
+		titleStyle.Render(tr("diff.title")),
+		helpStyle.Render("A: " + r.Before),
+		helpStyle.Render("B: " + r.After),
+		"",
+	}
+
+	sections := []struct {
+		title  string
+		color  string
+		groups []diffGroup
+	}{
+		{tr("diff.new"), "red", r.New},
+		{tr("diff.increased"), "orange", r.Increased},
+		{tr("diff.decreased"), "green", r.Decreased},
+		{tr("diff.gone"), "gray", r.Gone},
+	}
+	for _, sec := range sections {
+		header := lipgloss.NewStyle().Bold(true).Foreground(currentTheme.colors[sec.color])
+		lines = append(lines, header.Render(fmt.Sprintf("%s (%d)", sec.title, len(sec.groups))))
+		for _, g := range sec.groups {
+			severityStyle := lipgloss.NewStyle().Foreground(severityColor(g.Severity))
+			lines = append(lines, logLineStyle.Render(fmt.Sprintf("%6d → %-6d %s %s",
+				g.Before, g.After, severityStyle.Render(fmt.Sprintf("%-5s", g.Severity)), g.Message)))
+		}
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// runDiff vergleicht auf der Kommandozeile:
+// "analyzer diff [-window 24h] <quelle> [<quelle>]"
+func runDiff(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	window := fs.Duration("window", 24*time.Hour, "time window when comparing one source")
+	fs.Parse(args)
+
+	var result diffResult
+	var err error
+	switch fs.NArg() {
+	case 1:
+		result, err = compareWindows(cfg, cfg.lookupSource(fs.Arg(0)), *window)
+	case 2:
+		result, err = compareSources(cfg, cfg.lookupSource(fs.Arg(0)), cfg.lookupSource(fs.Arg(1)))
+	default:
+		return errorf("diff.usage")
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, line := range renderDiff(result) {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// lookupSource sucht eine Quelle der Konfiguration anhand ihres Namens. Andere
+// Pfade werden als Datei mit automatischer Erkennung gelesen.
+func (c *Config) lookupSource(name string) LogConfig {
+	for _, logCfg := range c.Logs {
+		if logCfg.Name() == name {
+			return logCfg
+		}
+	}
+	return LogConfig{Path: name, index: c.Index, enricher: c.enricher}
+}
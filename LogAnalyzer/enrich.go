@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnrichmentConfig ergänzt die Einträge aller Quellen um Angaben zu IP und
+// User-Agent. Die Felder landen in Metadata und sind wie alle anderen
+// filterbar, z.B. mit "analyzer query -where country=DE".
+type EnrichmentConfig struct {
+	GeoIP     []string            `yaml:"geoip"`     // MaxMind-Datenbanken (.mmdb), z.B. GeoLite2-Country und GeoLite2-ASN
+	UserAgent bool                `yaml:"useragent"` // Browser, Betriebssystem und Bots erkennen
+	DNS       bool                `yaml:"dns"`       // Hostnamen per Reverse-DNS im Hintergrund nachschlagen
+	Networks  map[string][]string `yaml:"networks"`  // Label und Netze, z.B. office: ["10.1.0.0/16"]
+}
+
+// Wartezeit für eine Reverse-DNS-Anfrage
+const dnsTimeout = time.Second
+
+// Maximale Anzahl gemerkter Hostnamen, danach beginnt der Cache neu
+const dnsCacheSize = 10000
+
+// Anzahl gleichzeitiger Reverse-DNS-Anfragen und wartender IPs; weitere IPs
+// werden erst bei einem späteren Eintrag angefragt
+const (
+	dnsWorkers = 8
+	dnsQueue   = 1000
+)
+
+// labeledNetwork ist ein Netz mit seinem Label aus networks
+type labeledNetwork struct {
+	network *net.IPNet
+	label   string
+	bits    int
+}
+
+// enricher ergänzt Einträge nach dem Parsen. Er wird von allen Quellen
+// gemeinsam genutzt, auch aus mehreren Goroutinen.
+type enricher struct {
+	databases []*mmdb
+	networks  []labeledNetwork // spezifischste Netze zuerst
+	userAgent bool
+	dns       bool
+
+	mu        sync.Mutex
+	hostnames map[string]string
+	resolving map[string]bool // angefragt, aber noch nicht beantwortet
+	queue     chan string
+	lookupDNS func(ctx context.Context, addr string) ([]string, error)
+}
+
+// newEnricher öffnet die Datenbanken und prüft die Netze. Ohne Einstellungen
+// liefert sie nil, die Einträge bleiben dann unverändert.
+func newEnricher(cfg EnrichmentConfig) (*enricher, error) {
+	if len(cfg.GeoIP) == 0 && !cfg.UserAgent && !cfg.DNS && len(cfg.Networks) == 0 {
+		return nil, nil
+	}
+	e := &enricher{
+		userAgent: cfg.UserAgent,
+		dns:       cfg.DNS,
+		hostnames: map[string]string{},
+		resolving: map[string]bool{},
+		lookupDNS: net.DefaultResolver.LookupAddr,
+	}
+	if e.dns {
+		e.queue = make(chan string, dnsQueue)
+		for i := 0; i < dnsWorkers; i++ {
+			go e.resolve()
+		}
+	}
+	for _, path := range cfg.GeoIP {
+		db, err := openMMDB(path)
+		if err != nil {
+			return nil, err
+		}
+		e.databases = append(e.databases, db)
+	}
+	for label, cidrs := range cfg.Networks {
+		for _, cidr := range cidrs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, errorf("enrich.network", cidr, label)
+			}
+			bits, _ := network.Mask.Size()
+			e.networks = append(e.networks, labeledNetwork{network: network, label: label, bits: bits})
+		}
+	}
+	sort.Slice(e.networks, func(i, j int) bool {
+		if e.networks[i].bits != e.networks[j].bits {
+			return e.networks[i].bits > e.networks[j].bits
+		}
+		return e.networks[i].label < e.networks[j].label
+	})
+	return e, nil
+}
+
+// fingerprint beschreibt die Einstellungen für den Index. Ändern sich
+// Datenbanken oder Netze, werden die Dateien neu indiziert.
+func (e *enricher) fingerprint() string {
+	if e == nil {
+		return ""
+	}
+	parts := []string{strconv.FormatBool(e.userAgent), strconv.FormatBool(e.dns)}
+	for _, db := range e.databases {
+		parts = append(parts, fmt.Sprintf("%s@%d", db.typ, db.epoch))
+	}
+	for _, n := range e.networks {
+		parts = append(parts, n.label+"="+n.network.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// enrich ergänzt einen Eintrag. Vorhandene Metadata-Felder werden nicht
+// überschrieben.
+func (e *enricher) enrich(entry LogEntry) LogEntry {
+	if e == nil {
+		return entry
+	}
+	fields := map[string]string{}
+	if ip := net.ParseIP(stripPort(entryIP(entry))); ip != nil {
+		e.enrichIP(ip, fields)
+	}
+	if e.userAgent {
+		if ua := entry.Metadata["userAgent"]; ua != "" {
+			parseUserAgent(ua, fields)
+		}
+	}
+	if len(fields) == 0 {
+		return entry
+	}
+
+	metadata := make(map[string]string, len(entry.Metadata)+len(fields))
+	for k, v := range entry.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range fields {
+		if v != "" && metadata[k] == "" {
+			metadata[k] = v
+		}
+	}
+	entry.Metadata = metadata
+	return entry
+}
+
+// enrichIP ergänzt Land, AS, Netz-Label und Hostname einer IP
+func (e *enricher) enrichIP(ip net.IP, fields map[string]string) {
+	for _, db := range e.databases {
+		record, err := db.lookup(ip)
+		if err != nil || record == nil {
+			continue
+		}
+		country := mmdbPath(record, "country", "iso_code")
+		if country == nil {
+			country = mmdbPath(record, "registered_country", "iso_code")
+		}
+		if s, ok := country.(string); ok {
+			fields["country"] = s
+		}
+		if s, ok := mmdbPath(record, "city", "names", "en").(string); ok {
+			fields["city"] = s
+		}
+		if asn := mmdbUint(record["autonomous_system_number"]); asn != 0 {
+			fields["asn"] = "AS" + strconv.FormatUint(asn, 10)
+		}
+		if s, ok := record["autonomous_system_organization"].(string); ok {
+			fields["asOrg"] = s
+		}
+	}
+	for _, n := range e.networks {
+		if n.network.Contains(ip) {
+			fields["network"] = n.label
+			break
+		}
+	}
+	if e.dns {
+		fields["hostname"] = e.hostname(ip.String())
+	}
+}
+
+// hostname liefert den gemerkten Namen einer IP. Unbekannte IPs werden im
+// Hintergrund nachgeschlagen, damit das Parsen nie auf DNS wartet; bis zur
+// Antwort bleibt der Hostname leer.
+func (e *enricher) hostname(ip string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if name, ok := e.hostnames[ip]; ok || e.resolving[ip] {
+		return name
+	}
+	select {
+	case e.queue <- ip:
+		e.resolving[ip] = true
+	default:
+	}
+	return ""
+}
+
+// resolve beantwortet die angefragten IPs. Auch erfolglose Anfragen werden
+// gemerkt, damit jede IP nur einmal angefragt wird.
+func (e *enricher) resolve() {
+	for ip := range e.queue {
+		name := ""
+		ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+		if names, err := e.lookupDNS(ctx, ip); err == nil && len(names) > 0 {
+			name = strings.TrimSuffix(names[0], ".")
+		}
+		cancel()
+
+		e.mu.Lock()
+		if len(e.hostnames) >= dnsCacheSize {
+			e.hostnames = map[string]string{}
+		}
+		e.hostnames[ip] = name
+		delete(e.resolving, ip)
+		e.mu.Unlock()
+	}
+}
+
+// uaRule erkennt einen Browser oder ein Betriebssystem am User-Agent
+type uaRule struct {
+	re   *regexp.Regexp
+	name string
+}
+
+// Bots und Werkzeuge; der Name wird aus dem User-Agent übernommen
+var (
+	botRegex  = regexp.MustCompile(`(?i)([\w.-]*(?:bot|crawler|spider|slurp)[\w.-]*)`)
+	toolRegex = regexp.MustCompile(`(?i)^(curl|wget|python-requests|python-urllib|go-http-client|java|okhttp|libwww-perl|scrapy|apache-httpclient|axios|node-fetch)\b`)
+)
+
+// Browser in der Reihenfolge der Prüfung: Chrome-Ableger vor Chrome, Chrome
+// vor Safari. Die erste Gruppe ist die Version.
+var browserRules = []uaRule{
+	{regexp.MustCompile(`(?:mirall|Nextcloud-android|Nextcloud-iOS)/([\d.]+)`), "Nextcloud"},
+	{regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`), "Edge"},
+	{regexp.MustCompile(`OPR/([\d.]+)`), "Opera"},
+	{regexp.MustCompile(`SamsungBrowser/([\d.]+)`), "Samsung Internet"},
+	{regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`), "Firefox"},
+	{regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`), "Chrome"},
+	{regexp.MustCompile(`Version/([\d.]+).*Safari/`), "Safari"},
+	{regexp.MustCompile(`(?:MSIE |Trident/.*rv:)([\d.]+)`), "Internet Explorer"},
+}
+
+var osRules = []uaRule{
+	{regexp.MustCompile(`Windows`), "Windows"},
+	{regexp.MustCompile(`Android`), "Android"},
+	{regexp.MustCompile(`iPhone|iPad|iPod|iOS`), "iOS"},
+	{regexp.MustCompile(`Mac OS X|Macintosh`), "macOS"},
+	{regexp.MustCompile(`CrOS`), "ChromeOS"},
+	{regexp.MustCompile(`Linux|X11`), "Linux"},
+}
+
+// parseUserAgent ergänzt browser, browserVersion, os und bot
+func parseUserAgent(ua string, fields map[string]string) {
+	if m := botRegex.FindStringSubmatch(ua); m != nil {
+		fields["bot"] = m[1]
+	} else if m := toolRegex.FindStringSubmatch(ua); m != nil {
+		fields["bot"] = m[1]
+	}
+	for _, r := range browserRules {
+		if m := r.re.FindStringSubmatch(ua); m != nil {
+			fields["browser"] = r.name
+			// Nur die Hauptversion, damit sich gut danach filtern lässt
+			fields["browserVersion"], _, _ = strings.Cut(m[1], ".")
+			break
+		}
+	}
+	for _, r := range osRules {
+		if r.re.MatchString(ua) {
+			fields["os"] = r.name
+			break
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errorLogLevels bildet die Level von Apache und nginx auf levelOrder ab
+var errorLogLevels = map[string]string{
+	"emerg":  "fatal",
+	"alert":  "fatal",
+	"crit":   "fatal",
+	"error":  "error",
+	"warn":   "warn",
+	"notice": "info",
+	"info":   "info",
+	"debug":  "debug",
+}
+
+// errorLogLevel liefert das Level; trace1 bis trace8 gelten als debug
+func errorLogLevel(level string) (string, bool) {
+	level = strings.ToLower(level)
+	if sev, ok := errorLogLevels[level]; ok {
+		return sev, true
+	}
+	if len(level) == 6 && strings.HasPrefix(level, "trace") && level[5] >= '1' && level[5] <= '8' {
+		return "debug", true
+	}
+	return "", false
+}
+
+// Apache 2.4 error_log, z.B.
+// [Thu Oct 10 13:55:36.123456 2024] [core:error] [pid 1234:tid 5678] [client 192.0.2.1:51234] AH00126: Invalid URI
+// Ältere Versionen schreiben nur [error] und keine pid.
+var apacheErrorRegex = regexp.MustCompile(`^\[([^\]]+)\] \[(?:([^:\]]*):)?([^\]]+)\](?: \[pid (\d+)(?::tid (\d+))?\])?(?: \[(?:client|remote) ([^\]]+)\])? ?(.*)$`)
+
+// Fehlernummern von Apache wie AH00126
+var apacheErrorCodeRegex = regexp.MustCompile(`^(AH\d{5}): `)
+
+type ApacheErrorParser struct {
+	time timeParser
+}
+
+func (p *ApacheErrorParser) Parse(line string) (LogEntry, error) {
+	match := apacheErrorRegex.FindStringSubmatch(line)
+	if match == nil {
+		return LogEntry{}, errorf("parser.apacheError")
+	}
+	sev, ok := errorLogLevel(match[3])
+	if !ok {
+		return LogEntry{}, errorf("parser.apacheError")
+	}
+	t, err := p.time.parse(match[1], "Mon Jan 02 15:04:05.000000 2006", "Mon Jan 02 15:04:05 2006")
+	if err != nil {
+		return LogEntry{}, err
+	}
+
+	message := match[7]
+	metadata := map[string]string{
+		"module":     match[2],
+		"pid":        match[4],
+		"remoteAddr": stripPort(match[6]),
+	}
+	if code := apacheErrorCodeRegex.FindStringSubmatch(message); code != nil {
+		metadata["code"] = code[1]
+		message = message[len(code[0]):]
+	}
+	if i := strings.LastIndex(message, ", referer: "); i >= 0 {
+		metadata["referer"] = message[i+len(", referer: "):]
+		message = message[:i]
+	}
+
+	return LogEntry{
+		Timestamp: t,
+		Source:    "apache",
+		Severity:  sev,
+		Message:   message,
+		Metadata:  metadata,
+	}, nil
+}
+
+// stripPort entfernt den Port einer Adresse. Apache schreibt IPv6-Adressen
+// ohne Klammern, z.B. "::1:51234".
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	if i := strings.LastIndex(addr, ":"); i > 0 && net.ParseIP(addr[:i]) != nil {
+		if _, err := strconv.Atoi(addr[i+1:]); err == nil {
+			return addr[:i]
+		}
+	}
+	return addr
+}
+
+// nginx error.log, z.B.
+// 2024/10/10 13:55:36 [error] 1234#5678: *99 open() "/var/www/x" failed (2: No such file or directory), client: 192.0.2.1, server: example.com, request: "GET /x HTTP/1.1", host: "example.com"
+var nginxErrorRegex = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] (\d+)#(\d+): (?:\*(\d+) )?(.*)$`)
+
+// Angaben, die nginx an die Meldung anhängt
+var nginxErrorFieldRegex = regexp.MustCompile(`, (client|server|request|subrequest|upstream|host|referrer): ("[^"]*"|[^,]*)`)
+
+type NginxErrorParser struct {
+	time timeParser
+}
+
+func (p *NginxErrorParser) Parse(line string) (LogEntry, error) {
+	match := nginxErrorRegex.FindStringSubmatch(line)
+	if match == nil {
+		return LogEntry{}, errorf("parser.nginxError")
+	}
+	sev, ok := errorLogLevel(match[2])
+	if !ok {
+		return LogEntry{}, errorf("parser.nginxError")
+	}
+	t, err := p.time.parse(match[1], "2006/01/02 15:04:05")
+	if err != nil {
+		return LogEntry{}, err
+	}
+
+	message := match[6]
+	metadata := map[string]string{
+		"pid":        match[3],
+		"connection": match[5],
+	}
+	if fields := nginxErrorFieldRegex.FindAllStringSubmatchIndex(message, -1); fields != nil {
+		for _, f := range fields {
+			key, value := message[f[2]:f[3]], strings.Trim(message[f[4]:f[5]], `"`)
+			switch key {
+			case "client":
+				metadata["remoteAddr"] = value
+			case "referrer":
+				metadata["referer"] = value
+			case "request":
+				if parts := strings.Fields(value); len(parts) == 3 {
+					metadata["method"] = parts[0]
+					metadata["url"] = parts[1]
+					metadata["protocol"] = parts[2]
+				}
+				metadata[key] = value
+			default:
+				metadata[key] = value
+			}
+		}
+		message = message[:fields[0][0]]
+	}
+
+	return LogEntry{
+		Timestamp: t,
+		Source:    "nginx",
+		Severity:  sev,
+		Message:   message,
+		Metadata:  metadata,
+	}, nil
+}
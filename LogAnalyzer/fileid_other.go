@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileID kennzeichnet eine Datei über ihren absoluten Pfad; umbenannte
+// Dateien werden daher neu indiziert
+func fileID(path string, info os.FileInfo) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileID kennzeichnet eine Datei über Gerät und Inode, damit sie auch nach dem
+// Umbenennen durch logrotate wiedererkannt wird
+func fileID(path string, info os.FileInfo) string {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d-%d", st.Dev, st.Ino)
+	}
+	return path
+}
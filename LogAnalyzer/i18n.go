@@ -0,0 +1,98 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Meldungskataloge je Sprache, z.B. locales/de.yaml
+//
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// Sprache, wenn weder config.yaml noch Umgebung eine unterstützte nennen
+const defaultLanguage = "en"
+
+var (
+	catalogs = loadCatalogs()
+	language = defaultLanguage
+)
+
+// loadCatalogs liest alle eingebetteten Kataloge
+func loadCatalogs() map[string]map[string]string {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	catalogs := map[string]map[string]string{}
+	for _, f := range files {
+		data, err := localeFiles.ReadFile("locales/" + f.Name())
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("locales/%s: %v", f.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(f.Name(), ".yaml")] = messages
+	}
+	return catalogs
+}
+
+// detectLanguage ermittelt die Sprache aus LC_ALL, LC_MESSAGES bzw. LANG,
+// z.B. "de_DE.UTF-8"
+func detectLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		lang := strings.ToLower(value)
+		if i := strings.IndexAny(lang, "_.@-"); i >= 0 {
+			lang = lang[:i]
+		}
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+		// Die erste gesetzte Variable gilt, auch wenn ihre Sprache fehlt
+		break
+	}
+	return defaultLanguage
+}
+
+// setLanguage wählt die Sprache der Oberfläche; leer erkennt sie aus der Umgebung
+func setLanguage(lang string) error {
+	if lang == "" {
+		lang = detectLanguage()
+	}
+	if _, ok := catalogs[lang]; !ok {
+		return errorf("config.language", lang)
+	}
+	language = lang
+	return nil
+}
+
+// tr liefert die Meldung id in der gewählten Sprache, mit args formatiert.
+// Fehlt sie im Katalog, wird auf Englisch und zuletzt auf die id ausgewichen.
+func tr(id string, args ...interface{}) string {
+	msg, ok := catalogs[language][id]
+	if !ok {
+		if msg, ok = catalogs[defaultLanguage][id]; !ok {
+			msg = id
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// errorf erstellt einen Fehler mit der übersetzten Meldung id
+func errorf(id string, args ...interface{}) error {
+	return errors.New(tr(id, args...))
+}
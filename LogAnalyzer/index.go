@@ -0,0 +1,564 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Version des Index-Aufbaus; bei Änderungen an Parsern oder Format erhöhen,
+// damit bestehende Indizes neu aufgebaut werden
+const indexVersion = "1"
+
+// Zeilen je Schreibtransaktion, damit ein abgebrochener Lauf fortgesetzt wird
+const indexBatchLines = 10000
+
+// Anzahl Bytes am Dateianfang, an denen eine Datei wiedererkannt wird
+const indexHeadSize = 1024
+
+var (
+	bucketFiles    = []byte("files")    // Datei-ID → indexFile
+	bucketEntries  = []byte("entries")  // je Datei: Offset → LogEntry
+	bucketTimes    = []byte("times")    // je Datei: Zeit und Offset → leer
+	bucketUnparsed = []byte("unparsed") // je Datei: Offset → Zeile
+)
+
+// errUnparsed kennzeichnet Rohzeilen aus dem Index
+var errUnparsed = errors.New("unparsed")
+
+// indexFile beschreibt den Stand einer indizierten Datei
+type indexFile struct {
+	Path     string         `json:"path"`
+	Offset   int64          `json:"offset"` // bis hierhin indiziert
+	Config   string         `json:"config"` // Parser-Einstellungen beim Indizieren
+	Head     string         `json:"head"`   // Hash des Dateianfangs
+	HeadLen  int64          `json:"headLen"`
+	Type     string         `json:"type"`
+	Parsed   int            `json:"parsed"`
+	Failed   int            `json:"failed"`
+	Levels   map[string]int `json:"levels"`
+	Complete bool           `json:"complete"` // komprimierte Dateien werden nur einmal gelesen
+}
+
+// indexed meldet, ob die Quelle über den Index gelesen wird. Kommandos und
+// stdin haben keine Datei, deren Stand sich merken ließe.
+func (c LogConfig) indexed() bool {
+	return c.index != "" && c.Command == "" && !c.isStdin()
+}
+
+// openIndex öffnet die Index-Datei. Hält ein anderer Prozess sie offen, wird
+// nach kurzer Zeit aufgegeben.
+func openIndex(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+}
+
+// indexFingerprint fasst die Einstellungen zusammen, die das Parsen
+// beeinflussen. Bei Plugins zählt das Kommando, da ein anderes Skript andere
+// Einträge liefern kann.
+func indexFingerprint(cfg LogConfig) string {
+	plugin := ""
+	if cfg.plugin != nil {
+		plugin = cfg.plugin.Command
+	}
+	return strings.Join([]string{indexVersion, cfg.Type, plugin, cfg.TimeFormat, cfg.LogDateFormat, cfg.Timezone, cfg.LogFormat, cfg.enricher.fingerprint(), severityFingerprint(cfg.Severity)}, "|")
+}
+
+// Endungen rotierter Dateien, z.B. access.log.1, access.log.2.gz oder
+// access.log-20241010
+var rotatedSuffixRegex = regexp.MustCompile(`^(\.\d+|-\d{8,10})(\.gz)?$`)
+
+// sourceFiles liefert die rotierten Dateien einer Quelle, die ältesten zuerst,
+// und zuletzt die aktuelle Datei
+func sourceFiles(path string) []string {
+	dotted, _ := filepath.Glob(path + ".*")
+	dashed, _ := filepath.Glob(path + "-*")
+	type rotated struct {
+		path    string
+		modTime time.Time
+	}
+	var files []rotated
+	for _, m := range append(dotted, dashed...) {
+		if !rotatedSuffixRegex.MatchString(strings.TrimPrefix(m, path)) {
+			continue
+		}
+		if info, err := os.Stat(m); err == nil {
+			files = append(files, rotated{m, info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	paths := make([]string, 0, len(files)+1)
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	return append(paths, path)
+}
+
+// headHash berechnet den Hash der ersten n Bytes einer Datei
+func headHash(file *os.File, n int64) (string, error) {
+	buf := make([]byte, n)
+	if _, err := file.ReadAt(buf, 0); err != nil && err != io.EOF {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func offsetKey(offset int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(offset))
+	return key
+}
+
+// timeKey sortiert nach Zeit, bei gleicher Zeit nach Offset
+func timeKey(t time.Time, offset int64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano())^(1<<63))
+	binary.BigEndian.PutUint64(key[8:], uint64(offset))
+	return key
+}
+
+func fileBucketName(id string) []byte {
+	return []byte("file:" + id)
+}
+
+// indexedLine ist ein Parse-Ergebnis mit der Position der Zeile in der Datei
+type indexedLine struct {
+	parsedLine
+	offset int64
+}
+
+// updateIndexFile indiziert die noch nicht erfassten Zeilen einer Datei.
+// Gekürzte, ersetzte oder anders konfigurierte Dateien werden neu indiziert.
+// Eine unvollständige letzte Zeile und Zeilen, die der Reader noch
+// zurückhält, bleiben für den nächsten Lauf.
+func updateIndexFile(db *bolt.DB, cfg LogConfig, path string) (indexFile, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return indexFile{}, "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return indexFile{}, "", err
+	}
+	id := fileID(path, info)
+	gz := strings.HasSuffix(path, ".gz")
+	fingerprint := indexFingerprint(cfg)
+
+	var meta indexFile
+	found := false
+	err = db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(bucketFiles); b != nil {
+			if data := b.Get([]byte(id)); data != nil {
+				found = true
+				return json.Unmarshal(data, &meta)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return meta, id, err
+	}
+
+	// Bei komprimierten Dateien zählt Offset die entpackten Bytes; sie werden
+	// nur an Datei-ID und Anfang wiedererkannt
+	reset := !found || meta.Config != fingerprint || (gz && !meta.Complete) || (!gz && info.Size() < meta.Offset)
+	if !reset && meta.HeadLen > 0 {
+		head, err := headHash(file, meta.HeadLen)
+		if err != nil {
+			return meta, id, err
+		}
+		reset = head != meta.Head
+	}
+	if !reset && (meta.Complete || info.Size() == meta.Offset) {
+		return meta, id, nil
+	}
+	if reset {
+		meta = indexFile{Config: fingerprint, Levels: map[string]int{}}
+	}
+	meta.Path = path
+
+	var r io.Reader = file
+	offset := meta.Offset
+	if gz {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return meta, id, err
+		}
+		defer zr.Close()
+		r = zr
+	} else if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return meta, id, err
+	}
+
+	// Mit bereits erkanntem Typ weiterlesen
+	parserCfg := cfg
+	if meta.Type != "" {
+		parserCfg.Type = meta.Type
+	}
+	reader, err := newEntryReader(parserCfg)
+	if err != nil {
+		return meta, id, err
+	}
+
+	write := func(batch []indexedLine, offset int64, complete bool) error {
+		return db.Update(func(tx *bolt.Tx) error {
+			if reset {
+				// Veraltete Einträge erst beim ersten Schreiben verwerfen
+				if tx.Bucket(fileBucketName(id)) != nil {
+					if err := tx.DeleteBucket(fileBucketName(id)); err != nil {
+						return err
+					}
+				}
+				reset = false
+			}
+			fb, err := tx.CreateBucketIfNotExists(fileBucketName(id))
+			if err != nil {
+				return err
+			}
+			buckets := map[string]*bolt.Bucket{}
+			for _, name := range [][]byte{bucketEntries, bucketTimes, bucketUnparsed} {
+				if buckets[string(name)], err = fb.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+
+			for _, l := range batch {
+				key := offsetKey(l.offset)
+				if l.err != nil {
+					meta.Failed++
+					if err := buckets[string(bucketUnparsed)].Put(key, []byte(l.line)); err != nil {
+						return err
+					}
+					continue
+				}
+				data, err := json.Marshal(l.entry)
+				if err != nil {
+					return err
+				}
+				if err := buckets[string(bucketEntries)].Put(key, data); err != nil {
+					return err
+				}
+				if err := buckets[string(bucketTimes)].Put(timeKey(l.entry.Timestamp, l.offset), nil); err != nil {
+					return err
+				}
+				meta.Parsed++
+				meta.Levels[l.entry.Severity]++
+			}
+
+			meta.Offset = offset
+			meta.Type = reader.typ
+			meta.Complete = complete
+			if meta.HeadLen < indexHeadSize {
+				meta.HeadLen = min(info.Size(), indexHeadSize)
+				if !gz {
+					meta.HeadLen = min(offset, indexHeadSize)
+				}
+				if meta.Head, err = headHash(file, meta.HeadLen); err != nil {
+					return err
+				}
+			}
+			data, err := json.Marshal(meta)
+			if err != nil {
+				return err
+			}
+			files, err := tx.CreateBucketIfNotExists(bucketFiles)
+			if err != nil {
+				return err
+			}
+			return files.Put([]byte(id), data)
+		})
+	}
+
+	var batch []indexedLine
+	var pending []int64 // Offsets der Zeilen, für die noch kein Ergebnis geliefert wurde
+	collect := func(results []parsedLine) {
+		for i, p := range results {
+			batch = append(batch, indexedLine{parsedLine: p, offset: pending[i]})
+		}
+		pending = pending[len(results):]
+	}
+	// Bis safe sind alle Zeilen als Ergebnis geliefert; nur so weit wird der
+	// Stand gespeichert
+	safe := offset
+	settle := func() {
+		if !reader.holding() {
+			safe = offset
+			pending = nil
+		}
+	}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return meta, id, err
+		}
+		// Unvollständige Zeile am Ende einer wachsenden Datei später lesen
+		if err == io.EOF && (!gz || line == "") {
+			break
+		}
+		start := offset
+		offset += int64(len(line))
+		text := strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(text) != "" {
+			pending = append(pending, start)
+		}
+		collect(reader.add(text))
+		settle()
+
+		// Teilzeilen ergeben kein Ergebnis; die Einträge erhalten dann den
+		// Offset einer früheren Zeile, bleiben aber eindeutig und geordnet
+		if len(batch) >= indexBatchLines && safe == offset {
+			if err := write(batch, offset, false); err != nil {
+				return meta, id, err
+			}
+			batch = nil
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	// Komprimierte Dateien wachsen nicht mehr, ihre Teilzeilen sind vollständig
+	if gz {
+		collect(reader.idle())
+	} else {
+		collect(reader.flush())
+	}
+	if reader.err != nil {
+		return meta, id, reader.err
+	}
+	settle()
+	// Ergebnisse hinter safe stammen aus Zeilen, die der Reader noch
+	// zurückhält; sie werden im nächsten Lauf mit diesen neu gelesen
+	kept := batch[:0]
+	for _, l := range batch {
+		if l.offset < safe {
+			kept = append(kept, l)
+		}
+	}
+	if err := write(kept, safe, gz); err != nil {
+		return meta, id, err
+	}
+	return meta, id, nil
+}
+
+// readIndexFile liefert Einträge und Rohzeilen einer Datei in ihrer
+// Reihenfolge, bis fn false liefert
+func readIndexFile(db *bolt.DB, id string, fn func(p parsedLine) bool) error {
+	return db.View(func(tx *bolt.Tx) error {
+		fb := tx.Bucket(fileBucketName(id))
+		if fb == nil {
+			return nil
+		}
+		entries := fb.Bucket(bucketEntries).Cursor()
+		unparsed := fb.Bucket(bucketUnparsed).Cursor()
+		ek, ev := entries.First()
+		uk, uv := unparsed.First()
+		for ek != nil || uk != nil {
+			if uk != nil && (ek == nil || bytes.Compare(uk, ek) < 0) {
+				if !fn(parsedLine{line: string(uv), err: errUnparsed}) {
+					return nil
+				}
+				uk, uv = unparsed.Next()
+				continue
+			}
+			var e LogEntry
+			if err := json.Unmarshal(ev, &e); err != nil {
+				return err
+			}
+			if !fn(parsedLine{entry: e}) {
+				return nil
+			}
+			ek, ev = entries.Next()
+		}
+		return nil
+	})
+}
+
+// indexedEntries aktualisiert den Index einer Datei und liest ihn wie
+// readEntries: alle Einträge ab dem loglevel der Quelle
+func indexedEntries(cfg LogConfig) ([]LogEntry, *entryReader, error) {
+	db, err := openIndex(cfg.index)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	meta, id, err := updateIndexFile(db, cfg, cfg.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []LogEntry
+	err = readIndexFile(db, id, func(p parsedLine) bool {
+		if p.err == nil && shouldLog(cfg.LogLevel, p.entry.Severity) {
+			entries = append(entries, p.entry)
+		}
+		return true
+	})
+	reader := &entryReader{cfg: cfg, typ: meta.Type, parsed: meta.Parsed, failed: meta.Failed}
+	return entries, reader, err
+}
+
+// readIndexed liest die aktuelle Quelle aus dem Index. Nach der maximalen
+// Anzahl Einträge wird abgebrochen, die Level-Zähler stammen aus dem Index.
+func (m *model) readIndexed() error {
+	db, err := openIndex(m.currentLog.index)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	meta, id, err := updateIndexFile(db, m.currentLog, m.currentLog.Path)
+	if err != nil {
+		return err
+	}
+	err = readIndexFile(db, id, func(p parsedLine) bool {
+		m.addParsed([]parsedLine{p})
+		return !m.truncated
+	})
+	if err != nil {
+		return err
+	}
+
+	m.reader.typ = meta.Type
+	m.reader.parsed = meta.Parsed
+	m.reader.failed = meta.Failed
+	for level, n := range meta.Levels {
+		m.levelCounts[level] = n
+	}
+	return nil
+}
+
+// queryIndex sucht in allen Dateien einer Quelle einschließlich der
+// rotierten. Geliefert werden die letzten limit Treffer, nach Zeit sortiert.
+func queryIndex(cfg LogConfig, filter entryFilter, limit int) ([]LogEntry, error) {
+	db, err := openIndex(cfg.index)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var ids []string
+	for _, path := range sourceFiles(cfg.Path) {
+		_, id, err := updateIndexFile(db, cfg, path)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	var results []LogEntry
+	err = db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			fb := tx.Bucket(fileBucketName(id))
+			if fb == nil {
+				continue
+			}
+			entries := fb.Bucket(bucketEntries)
+			times := fb.Bucket(bucketTimes).Cursor()
+
+			var k []byte
+			if filter.from.IsZero() {
+				k, _ = times.First()
+			} else {
+				k, _ = times.Seek(timeKey(filter.from, 0))
+			}
+			end := timeKey(filter.to, 1<<62)
+			for ; k != nil; k, _ = times.Next() {
+				if !filter.to.IsZero() && bytes.Compare(k, end) > 0 {
+					break
+				}
+				var e LogEntry
+				if err := json.Unmarshal(entries.Get(k[8:]), &e); err != nil {
+					return err
+				}
+				if filter.match(e) {
+					results = append(results, e)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Timestamp.Before(results[j].Timestamp) })
+	if limit > 0 && len(results) > limit {
+		results = results[len(results)-limit:]
+	}
+	return results, nil
+}
+
+// runQuery durchsucht den Index einer Quelle einschließlich rotierter Dateien:
+// "analyzer query [-from] [-to] [-level] [-where key=value]... [-q] <source>"
+func runQuery(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	from := fs.String("from", "", "start time (RFC3339 or 2006-01-02T15:04)")
+	to := fs.String("to", "", "end time")
+	level := fs.String("level", "", "minimum severity")
+	text := fs.String("q", "", "text in message or metadata")
+	limit := fs.Int("limit", 1000, "print at most the newest n entries, 0 prints all")
+	var where []string
+	fs.Func("where", "metadata filter key=value, repeatable", func(value string) error {
+		where = append(where, value)
+		return nil
+	})
+	fs.Parse(args)
+
+	if cfg.Index == "" {
+		return errorf("index.disabled")
+	}
+	if fs.NArg() != 1 {
+		return errorf("index.usage")
+	}
+	logCfg := cfg.lookupSource(fs.Arg(0))
+	if !logCfg.indexed() {
+		return errorf("index.noFile", logCfg.Name())
+	}
+
+	filter := entryFilter{level: *level, text: strings.ToLower(*text)}
+	if filter.level != "" {
+		if _, ok := levelOrder[filter.level]; !ok {
+			return errorf("server.unknownLevel", filter.level)
+		}
+	}
+	var err error
+	if filter.from, err = parseQueryTime(*from, cfg.displayLoc); err != nil {
+		return err
+	}
+	if filter.to, err = parseQueryTime(*to, cfg.displayLoc); err != nil {
+		return err
+	}
+	if filter.where, err = parseWhere(where); err != nil {
+		return err
+	}
+
+	entries, err := queryIndex(logCfg, filter, *limit)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		e = cfg.redact(e)
+		fmt.Printf("%s %-7s %s\n", cfg.displayTime(e.Timestamp).Format("2006-01-02 15:04:05"), e.Severity, e.Message)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Level in aufsteigender Reihenfolge, wie sie über die Tasten 1-5 geschaltet werden
+var severityLevels = []string{"debug", "info", "warn", "error", "fatal"}
+
+// levelFilter bestimmt, welche Level in der Ansicht gezeigt werden
+type levelFilter struct {
+	min    string          // Mindestlevel, anfangs das loglevel der Quelle
+	hidden map[string]bool // einzeln ausgeblendete Level
+}
+
+func newLevelFilter(cfg LogConfig) levelFilter {
+	return levelFilter{min: cfg.LogLevel, hidden: map[string]bool{}}
+}
+
+// shows prüft, ob Einträge mit diesem Level angezeigt werden
+func (f levelFilter) shows(severity string) bool {
+	return shouldLog(f.min, severity) && !f.hidden[severity]
+}
+
+// toggle blendet das Level mit dem Index i ein bzw. aus
+func (f *levelFilter) toggle(i int) {
+	if i < 0 || i >= len(severityLevels) {
+		return
+	}
+	level := severityLevels[i]
+	f.hidden[level] = !f.hidden[level]
+}
+
+// cycleMin schaltet das Mindestlevel weiter, nach fatal wieder auf debug
+func (f *levelFilter) cycleMin() {
+	next := (levelOrder[f.min] + 1) % len(severityLevels)
+	f.min = severityLevels[next]
+}
+
+// renderLevelCounts zeigt die Anzahl der Einträge je Level. Ausgeblendete
+// Level werden grau und durchgestrichen dargestellt.
+func (f levelFilter) renderLevelCounts(counts map[string]int) string {
+	parts := make([]string, len(severityLevels))
+	for i, level := range severityLevels {
+		text := fmt.Sprintf("%d %s: %d", i+1, level, counts[level])
+		style := lipgloss.NewStyle().Foreground(severityColor(level))
+		if !f.shows(level) {
+			style = helpStyle.Strikethrough(true)
+		}
+		parts[i] = style.Render(text)
+	}
+	return strings.Join(parts, helpStyle.Render(" | "))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricConfig beschreibt einen zusätzlichen Zähler für den Metrics-Modus
+type MetricConfig struct {
+	Name   string   `yaml:"name"`
+	Help   string   `yaml:"help"`
+	Source string   `yaml:"source"` // Name der Quelle, leer zählt alle Quellen
+	Labels []string `yaml:"labels"` // Metadata-Schlüssel, z.B. "status" oder "app"
+}
+
+// customCounter ist ein konfigurierter Zähler mit Labels aus den Metadaten
+type customCounter struct {
+	cfg     MetricConfig
+	counter *prometheus.CounterVec
+}
+
+// logMetrics enthält alle Zähler, die aus den Log-Einträgen abgeleitet werden
+type logMetrics struct {
+	config      *Config
+	entries     *prometheus.CounterVec
+	parseErrors *prometheus.CounterVec
+	custom      []customCounter
+}
+
+func newLogMetrics(cfg *Config, reg prometheus.Registerer) (*logMetrics, error) {
+	m := &logMetrics{
+		config: cfg,
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loganalyzer_entries_total",
+			Help: "Parsed log entries by source and severity.",
+		}, []string{"source", "severity"}),
+		parseErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loganalyzer_parse_errors_total",
+			Help: "Lines the parser of a source could not process.",
+		}, []string{"source"}),
+	}
+	if err := reg.Register(m.entries); err != nil {
+		return nil, err
+	}
+	if err := reg.Register(m.parseErrors); err != nil {
+		return nil, err
+	}
+
+	for _, mc := range cfg.Metrics {
+		help := mc.Help
+		if help == "" {
+			help = fmt.Sprintf("Log entries by %v.", mc.Labels)
+		}
+		counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: mc.Name,
+			Help: help,
+		}, append([]string{"source"}, mc.Labels...))
+		if err := reg.Register(counter); err != nil {
+			return nil, errorf("metrics.invalid", mc.Name, err)
+		}
+		m.custom = append(m.custom, customCounter{cfg: mc, counter: counter})
+	}
+	return m, nil
+}
+
+// observe zählt die Parse-Ergebnisse einer Quelle
+func (m *logMetrics) observe(source string, results []parsedLine) {
+	for _, p := range results {
+		if p.err != nil {
+			m.parseErrors.WithLabelValues(source).Inc()
+			continue
+		}
+		m.entries.WithLabelValues(source, p.entry.Severity).Inc()
+
+		for _, c := range m.custom {
+			if c.cfg.Source != "" && c.cfg.Source != source {
+				continue
+			}
+			values := []string{source}
+			for _, label := range c.cfg.Labels {
+				values = append(values, m.config.redactField(label, p.entry.Metadata[label]))
+			}
+			c.counter.WithLabelValues(values...).Inc()
+		}
+	}
+}
+
+// Wartezeit, bevor eine nicht lesbare Datei erneut geöffnet wird
+const metricsRetryDelay = 10 * time.Second
+
+// runMetrics startet den Exporter: "analyzer metrics [-addr :9100] [-from-start]"
+func runMetrics(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("metrics", flag.ExitOnError)
+	addr := fs.String("addr", ":9100", "listen address")
+	fromStart := fs.Bool("from-start", false, "count existing lines of log files")
+	fs.Parse(args)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	metrics, err := newLogMetrics(cfg, reg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, logCfg := range cfg.Logs {
+		go func(logCfg LogConfig) {
+			name := logCfg.Name()
+			fromEnd := !*fromStart
+			for ctx.Err() == nil {
+				err := tailEntries(ctx, logCfg, fromEnd, func(r *entryReader, results []parsedLine) {
+					metrics.observe(name, results)
+				})
+				if err != nil {
+					log.Print(tr("source.error", name, err))
+				}
+				// Kommandos und stdin würden beim Neustart doppelt gezählt
+				if logCfg.Command != "" || logCfg.isStdin() {
+					return
+				}
+				// Eine später angelegte Datei vollständig zählen, sonst nur neue Zeilen
+				fromEnd = !os.IsNotExist(err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(metricsRetryDelay):
+				}
+			}
+		}(logCfg)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	httpServer := &http.Server{
+		Addr:        *addr,
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Print(tr("metrics.listening", *addr))
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+)
+
+// Kennung vor den Metadaten am Ende einer MaxMind-Datenbank
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// Die Metadaten stehen in den letzten 128 KiB der Datei
+const mmdbMetadataMaxSize = 128 * 1024
+
+// mmdb liest Datenbanken im MaxMind-DB-Format, z.B. GeoLite2-Country oder
+// GeoLite2-ASN. Die Datei wird vollständig in den Speicher gelesen.
+type mmdb struct {
+	path       string
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	dataStart  uint   // Beginn des Datenbereichs
+	ipv4Start  uint   // Knoten für ::/96 in IPv6-Datenbanken
+	typ        string // database_type, z.B. "GeoLite2-ASN"
+	epoch      uint64 // build_epoch, ändert sich mit jeder Ausgabe
+}
+
+func openMMDB(path string) (*mmdb, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	search := data
+	if len(search) > mmdbMetadataMaxSize {
+		search = search[len(search)-mmdbMetadataMaxSize:]
+	}
+	i := bytes.LastIndex(search, mmdbMetadataMarker)
+	if i < 0 {
+		return nil, errorf("mmdb.format", path)
+	}
+	metaStart := uint(len(data)-len(search)+i) + uint(len(mmdbMetadataMarker))
+	meta, _, err := mmdbDecoder{data: data[metaStart:]}.decode(0)
+	if err != nil {
+		return nil, errorf("mmdb.invalid", path, err)
+	}
+	fields, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, errorf("mmdb.format", path)
+	}
+
+	db := &mmdb{path: path, data: data}
+	db.nodeCount = uint(mmdbUint(fields["node_count"]))
+	db.recordSize = uint(mmdbUint(fields["record_size"]))
+	db.ipVersion = uint(mmdbUint(fields["ip_version"]))
+	db.epoch = mmdbUint(fields["build_epoch"])
+	db.typ, _ = fields["database_type"].(string)
+	// Jeder Knoten belegt mindestens 6 Byte; größere Angaben würden bei der
+	// Berechnung des Suchbaums überlaufen
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 || db.nodeCount > uint(len(data)) {
+		return nil, errorf("mmdb.format", path)
+	}
+	treeSize := db.nodeCount * db.recordSize / 4
+	db.dataStart = treeSize + 16
+	if db.dataStart > metaStart {
+		return nil, errorf("mmdb.format", path)
+	}
+
+	// IPv4-Adressen liegen in IPv6-Datenbanken unter ::/96
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			if node, err = db.record(node, 0); err != nil {
+				return nil, err
+			}
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// record liefert den linken (bit 0) oder rechten (bit 1) Eintrag eines
+// Knotens. Liegt der Knoten hinter dem Ende der Datei, ist sie abgeschnitten.
+func (db *mmdb) record(node uint, bit uint) (uint, error) {
+	size := db.recordSize / 4 // Bytes je Knoten
+	if (node+1)*size > uint(len(db.data)) {
+		return 0, errorf("mmdb.format", db.path)
+	}
+	b := db.data[node*size : (node+1)*size]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	}
+	return uint(binary.BigEndian.Uint32(b[bit*4:])), nil
+}
+
+// lookup liefert den Datensatz zu einer IP oder nil, wenn keiner existiert
+func (db *mmdb) lookup(ip net.IP) (map[string]interface{}, error) {
+	node := uint(0)
+	addr := ip.To16()
+	if ip4 := ip.To4(); ip4 != nil {
+		addr = ip4
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < len(addr)*8 && node < db.nodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-uint(i%8))) & 1
+		var err error
+		if node, err = db.record(node, bit); err != nil {
+			return nil, err
+		}
+	}
+	if node <= db.nodeCount {
+		return nil, nil
+	}
+
+	offset := node - db.nodeCount - 16
+	value, _, err := mmdbDecoder{data: db.data[db.dataStart:]}.decode(offset)
+	if err != nil {
+		return nil, errorf("mmdb.invalid", db.path, err)
+	}
+	record, _ := value.(map[string]interface{})
+	return record, nil
+}
+
+// mmdbDecoder liest Werte aus dem Datenbereich
+type mmdbDecoder struct {
+	data []byte
+}
+
+// Datentypen im Datenbereich
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// decode liest den Wert bei offset und liefert den Offset dahinter. Zeiger
+// werden aufgelöst, der Offset zeigt dann hinter den Zeiger.
+func (d mmdbDecoder) decode(offset uint) (interface{}, uint, error) {
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ == mmdbPointer {
+		value, _, err := d.decode(size)
+		return value, offset, err
+	}
+	return d.decodeValue(typ, size, offset)
+}
+
+// control liest Typ und Größe eines Wertes; bei Zeigern ist size das Ziel
+func (d mmdbDecoder) control(offset uint) (typ, size, next uint, err error) {
+	if offset >= uint(len(d.data)) {
+		return 0, 0, 0, errorf("mmdb.offset", offset)
+	}
+	ctrl := d.data[offset]
+	offset++
+	typ = uint(ctrl >> 5)
+
+	if typ == mmdbPointer {
+		n := uint(ctrl>>3&0x3) + 1
+		if offset+n > uint(len(d.data)) {
+			return 0, 0, 0, errorf("mmdb.offset", offset)
+		}
+		b := d.data[offset : offset+n]
+		var p uint
+		if n < 4 {
+			p = uint(ctrl & 0x7)
+		}
+		for _, c := range b {
+			p = p<<8 | uint(c)
+		}
+		p += [...]uint{0, 2048, 526336, 0}[n-1]
+		return typ, p, offset + n, nil
+	}
+
+	if typ == mmdbExtended {
+		if offset >= uint(len(d.data)) {
+			return 0, 0, 0, errorf("mmdb.offset", offset)
+		}
+		typ = 7 + uint(d.data[offset])
+		offset++
+	}
+
+	size = uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.data)) {
+			return 0, 0, 0, errorf("mmdb.offset", offset)
+		}
+		var v uint
+		for _, c := range d.data[offset : offset+n] {
+			v = v<<8 | uint(c)
+		}
+		size = [...]uint{29, 285, 65821}[n-1] + v
+		offset += n
+	}
+	return typ, size, offset, nil
+}
+
+func (d mmdbDecoder) decodeValue(typ, size, offset uint) (interface{}, uint, error) {
+	switch typ {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, _ := key.(string)
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case mmdbArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	case mmdbContainer, mmdbEndMarker:
+		return nil, offset, nil
+	}
+
+	if offset+size > uint(len(d.data)) {
+		return nil, 0, errorf("mmdb.offset", offset)
+	}
+	b := d.data[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case mmdbString:
+		return string(b), next, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), next, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errorf("mmdb.offset", offset)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errorf("mmdb.offset", offset)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case mmdbInt32:
+		var v int32
+		for _, c := range b {
+			v = v<<8 | int32(c)
+		}
+		return int64(v), next, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbUint128:
+		// uint128 wird auf die unteren 64 Bit gekürzt
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, next, nil
+	}
+	return nil, 0, errorf("mmdb.type", typ)
+}
+
+// mmdbUint liefert eine Zahl aus den dekodierten Daten
+func mmdbUint(v interface{}) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		return uint64(n)
+	}
+	return 0
+}
+
+// mmdbPath liefert einen Wert in verschachtelten Maps, z.B. country.iso_code
+func mmdbPath(record map[string]interface{}, keys ...string) interface{} {
+	var value interface{} = record
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
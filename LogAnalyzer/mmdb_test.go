@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// mmdbValue kodiert Maps, Strings und Zahlen im Datenformat von MaxMind
+func mmdbValue(v interface{}) []byte {
+	control := func(typ byte, size int) []byte {
+		if size < 29 {
+			return []byte{typ<<5 | byte(size)}
+		}
+		return []byte{typ<<5 | 29, byte(size - 29)}
+	}
+	switch v := v.(type) {
+	case string:
+		return append(control(mmdbString, len(v)), v...)
+	case int:
+		b := binary.BigEndian.AppendUint32(nil, uint32(v))
+		return append(control(mmdbUint32, len(b)), b...)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := control(mmdbMap, len(v))
+		for _, k := range keys {
+			out = append(out, mmdbValue(k)...)
+			out = append(out, mmdbValue(v[k])...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// writeTestMMDB erstellt eine IPv4-Datenbank mit 24-Bit-Records, in der jedes
+// Netz auf seinen Datensatz zeigt
+func writeTestMMDB(t *testing.T, networks map[string]map[string]interface{}, meta map[string]interface{}) string {
+	t.Helper()
+	var cidrs []string
+	for cidr := range networks {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+
+	// Knoten mit ihren beiden Records; -1 ist leer, -2-i zeigt auf Datensatz i
+	nodes := [][2]int{{-1, -1}}
+	var data []byte
+	var offsets []int
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, len(data))
+		data = append(data, mmdbValue(networks[cidr])...)
+
+		bits, _ := network.Mask.Size()
+		node := 0
+		for b := 0; b < bits; b++ {
+			bit := int(network.IP.To4()[b/8]>>(7-b%8)) & 1
+			if b == bits-1 {
+				nodes[node][bit] = -2 - i
+				break
+			}
+			if nodes[node][bit] < 0 {
+				nodes = append(nodes, [2]int{-1, -1})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	var file []byte
+	for _, n := range nodes {
+		for _, r := range n {
+			v := r
+			switch {
+			case r == -1:
+				v = len(nodes)
+			case r < -1:
+				v = len(nodes) + 16 + offsets[-2-r]
+			}
+			file = append(file, byte(v>>16), byte(v>>8), byte(v))
+		}
+	}
+	file = append(file, make([]byte, 16)...)
+	file = append(file, data...)
+	file = append(file, mmdbMetadataMarker...)
+
+	fields := map[string]interface{}{
+		"node_count":    len(nodes),
+		"record_size":   24,
+		"ip_version":    4,
+		"database_type": "Test-Country",
+		"build_epoch":   1700000000,
+	}
+	for k, v := range meta {
+		fields[k] = v
+	}
+	file = append(file, mmdbValue(fields)...)
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var testNetworks = map[string]map[string]interface{}{
+	"1.2.3.0/24": {
+		"country":                        map[string]interface{}{"iso_code": "DE"},
+		"autonomous_system_number":       3320,
+		"autonomous_system_organization": "Deutsche Telekom AG",
+	},
+	"10.0.0.0/8": {
+		"registered_country": map[string]interface{}{"iso_code": "US"},
+	},
+}
+
+func TestMMDBLookup(t *testing.T) {
+	db, err := openMMDB(writeTestMMDB(t, testNetworks, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.typ != "Test-Country" || db.epoch != 1700000000 {
+		t.Errorf("metadata: type %q, epoch %d", db.typ, db.epoch)
+	}
+
+	record, err := db.lookup(net.ParseIP("1.2.3.4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mmdbPath(record, "country", "iso_code"); got != "DE" {
+		t.Errorf("country: got %v, want DE", got)
+	}
+	if got := mmdbUint(record["autonomous_system_number"]); got != 3320 {
+		t.Errorf("asn: got %d, want 3320", got)
+	}
+
+	if record, err := db.lookup(net.ParseIP("5.6.7.8")); err != nil || record != nil {
+		t.Errorf("unknown network: got %v, %v", record, err)
+	}
+	if record, err := db.lookup(net.ParseIP("2001:db8::1")); err != nil || record != nil {
+		t.Errorf("IPv6 in IPv4 database: got %v, %v", record, err)
+	}
+}
+
+func TestEnrichIPFromMMDB(t *testing.T) {
+	db, err := openMMDB(writeTestMMDB(t, testNetworks, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &enricher{databases: []*mmdb{db}}
+
+	fields := map[string]string{}
+	e.enrichIP(net.ParseIP("1.2.3.4"), fields)
+	if fields["country"] != "DE" || fields["asn"] != "AS3320" || fields["asOrg"] != "Deutsche Telekom AG" {
+		t.Errorf("1.2.3.4: got %v", fields)
+	}
+
+	fields = map[string]string{}
+	e.enrichIP(net.ParseIP("10.1.2.3"), fields)
+	if fields["country"] != "US" {
+		t.Errorf("10.1.2.3: got %v, want registered country US", fields)
+	}
+}
+
+func TestMMDBTruncatedTree(t *testing.T) {
+	db, err := openMMDB(writeTestMMDB(t, testNetworks, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nur die ersten Knoten sind noch vorhanden
+	db.data = db.data[:12]
+	if _, err := db.lookup(net.ParseIP("1.2.3.4")); err == nil {
+		t.Error("lookup in truncated tree succeeded")
+	}
+}
+
+func TestMMDBInvalidNodeCount(t *testing.T) {
+	path := writeTestMMDB(t, testNetworks, map[string]interface{}{"node_count": 1 << 30})
+	if _, err := openMMDB(path); err == nil {
+		t.Error("database with too many nodes was opened")
+	}
+}
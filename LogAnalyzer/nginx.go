@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standardformat "combined" von nginx
+const nginxCombinedFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+// Metadata-Schlüssel für nginx-Variablen; andere Variablen werden in
+// camelCase übernommen, z.B. $http_x_forwarded_for als httpXForwardedFor
+var nginxFields = map[string]string{
+	"remote_addr":            "remoteAddr",
+	"remote_user":            "user",
+	"body_bytes_sent":        "bytes",
+	"http_referer":           "referer",
+	"http_user_agent":        "userAgent",
+	"request_method":         "method",
+	"request_uri":            "url",
+	"server_protocol":        "protocol",
+	"request_time":           "requestTime",
+	"upstream_response_time": "upstreamTime",
+}
+
+// Variablen im log_format: $name oder ${name}
+var nginxVariableRegex = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// logFormat ist ein übersetztes log_format
+type logFormat struct {
+	re        *regexp.Regexp
+	variables []string
+}
+
+// compileLogFormat übersetzt ein log_format in einen regulären Ausdruck. Jede
+// Variable wird zu einer Gruppe, der Text dazwischen muss exakt passen.
+func compileLogFormat(format string) (*logFormat, error) {
+	f := &logFormat{}
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	hasTime := false
+	for _, m := range nginxVariableRegex.FindAllStringSubmatchIndex(format, -1) {
+		b.WriteString(regexp.QuoteMeta(format[last:m[0]]))
+		name := ""
+		if m[2] >= 0 {
+			name = format[m[2]:m[3]]
+		} else {
+			name = format[m[4]:m[5]]
+		}
+		switch name {
+		case "time_local", "time_iso8601", "msec":
+			hasTime = true
+		}
+		f.variables = append(f.variables, name)
+		b.WriteString("(.*?)")
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(format[last:]))
+	b.WriteString("$")
+
+	if !hasTime {
+		return nil, errorf("parser.nginxFormat")
+	}
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, err
+	}
+	f.re = re
+	return f, nil
+}
+
+// NginxParser liest Access-Logs im Format "combined" oder einem eigenen
+// log_format der Quelle
+type NginxParser struct {
+	time   timeParser
+	format *logFormat
+}
+
+// newNginxParser nutzt das log_format der Quelle. Ungültige Formate lehnt
+// bereits loadConfig ab.
+func newNginxParser(cfg LogConfig, ts timeParser) *NginxParser {
+	format, err := compileLogFormat(cfg.LogFormat)
+	if cfg.LogFormat == "" || err != nil {
+		format, _ = compileLogFormat(nginxCombinedFormat)
+	}
+	return &NginxParser{time: ts, format: format}
+}
+
+func (p *NginxParser) Parse(line string) (LogEntry, error) {
+	match := p.format.re.FindStringSubmatch(line)
+	if match == nil {
+		return LogEntry{}, errorf("parser.nginx")
+	}
+
+	var t time.Time
+	var err error
+	metadata := map[string]string{}
+	for i, name := range p.format.variables {
+		value := emptyDash(match[i+1])
+		switch name {
+		case "time_local":
+			t, err = p.time.parse(value, "02/Jan/2006:15:04:05 -0700")
+		case "time_iso8601":
+			t, err = p.time.parse(value, time.RFC3339)
+		case "msec":
+			t, err = parseMsec(value)
+		case "request":
+			if parts := strings.Fields(value); len(parts) == 3 {
+				metadata["method"] = parts[0]
+				metadata["url"] = parts[1]
+				metadata["protocol"] = parts[2]
+			}
+		default:
+			key, ok := nginxFields[name]
+			if !ok {
+				key = camelCase(name)
+			}
+			metadata[key] = value
+		}
+		if err != nil {
+			return LogEntry{}, err
+		}
+	}
+
+	status := metadata["status"]
+	if _, err := strconv.Atoi(status); status != "" && err != nil {
+		return LogEntry{}, errorf("parser.nginx")
+	}
+	message := strings.TrimSpace(fmt.Sprintf("%s %s %s %s", status, metadata["method"], metadata["url"], metadata["protocol"]))
+
+	return LogEntry{
+		Timestamp: t,
+		Source:    "nginx",
+		Severity:  statusSeverity(status),
+		Message:   message,
+		Metadata:  metadata,
+	}, nil
+}
+
+// parseMsec liest $msec, Sekunden seit 1970 mit Millisekunden
+func parseMsec(value string) (time.Time, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, errorf("time.unknownFormat", value)
+	}
+	return time.UnixMilli(int64(f * 1000)), nil
+}
+
+// camelCase wandelt z.B. "upstream_addr" in "upstreamAddr"
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
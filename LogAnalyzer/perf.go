@@ -0,0 +1,434 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Anzahl Balken der Verläufe und angezeigte Endpunkte bzw. langsamste Anfragen
+const (
+	perfBuckets   = 60
+	perfEndpoints = 30
+	perfSlowest   = 10
+)
+
+// Mögliche Intervalle der Verläufe; gewählt wird das kleinste, mit dem der
+// Zeitraum in perfBuckets Balken passt
+var perfIntervals = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// Pfadsegmente, die für Muster durch :id ersetzt werden: Zahlen, UUIDs und
+// lange Hex-Werte wie Hashes
+var idSegmentRegex = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// urlPattern fasst URLs mit IDs zusammen, z.B. /api/items/42?x=1 zu /api/items/:id
+func urlPattern(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	segments := strings.Split(url, "/")
+	for i, s := range segments {
+		if idSegmentRegex.MatchString(s) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// request ist ein Eintrag eines Access-Logs mit den ausgewerteten Angaben
+type request struct {
+	entry    LogEntry
+	latency  time.Duration
+	hasTime  bool
+	bytes    int64
+	failed   bool // Status 5xx
+	endpoint string
+}
+
+// accessRequest wertet einen Eintrag aus; Einträge ohne Status sind keine
+// Anfragen, z.B. aus Error-Logs
+func accessRequest(e LogEntry) (request, bool) {
+	status, err := strconv.Atoi(e.Metadata["status"])
+	if err != nil {
+		return request{}, false
+	}
+	r := request{
+		entry:    e,
+		failed:   status >= 500,
+		endpoint: strings.TrimSpace(e.Metadata["method"] + " " + urlPattern(e.Metadata["url"])),
+	}
+	r.bytes, _ = strconv.ParseInt(e.Metadata["bytes"], 10, 64)
+	// $request_time bzw. %D in Sekunden
+	if seconds, err := strconv.ParseFloat(e.Metadata["requestTime"], 64); err == nil {
+		r.latency = time.Duration(seconds * float64(time.Second))
+		r.hasTime = true
+	}
+	return r, true
+}
+
+// endpointStats sind die Kennzahlen eines URL-Musters
+type endpointStats struct {
+	Endpoint      string
+	Count         int
+	Errors        int
+	Bytes         int64
+	P50, P95, P99 time.Duration
+	latencies     []time.Duration
+}
+
+// perfBucket ist ein Balken des Verlaufs
+type perfBucket struct {
+	Start    time.Time
+	Requests int
+	Errors   int
+}
+
+// perfReport ist das Ergebnis der Auswertung
+type perfReport struct {
+	Requests  int
+	Errors    int
+	Bytes     int64
+	From, To  time.Time
+	Interval  time.Duration
+	Buckets   []perfBucket
+	Endpoints []*endpointStats
+	Slowest   []request
+}
+
+// analyzePerformance berechnet Latenzen, Verläufe und Datenmengen
+func analyzePerformance(entries []LogEntry) perfReport {
+	var report perfReport
+	var requests []request
+	byEndpoint := map[string]*endpointStats{}
+	for _, e := range entries {
+		r, ok := accessRequest(e)
+		if !ok {
+			continue
+		}
+		requests = append(requests, r)
+		report.Requests++
+		report.Bytes += r.bytes
+		if r.failed {
+			report.Errors++
+		}
+		// Einträge ohne Zeitstempel zählen mit, aber nicht für den Zeitraum
+		if !e.Timestamp.IsZero() {
+			if report.From.IsZero() || e.Timestamp.Before(report.From) {
+				report.From = e.Timestamp
+			}
+			if e.Timestamp.After(report.To) {
+				report.To = e.Timestamp
+			}
+		}
+
+		s := byEndpoint[r.endpoint]
+		if s == nil {
+			s = &endpointStats{Endpoint: r.endpoint}
+			byEndpoint[r.endpoint] = s
+			report.Endpoints = append(report.Endpoints, s)
+		}
+		s.Count++
+		s.Bytes += r.bytes
+		if r.failed {
+			s.Errors++
+		}
+		if r.hasTime {
+			s.latencies = append(s.latencies, r.latency)
+		}
+	}
+
+	for _, s := range report.Endpoints {
+		sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+		s.P50 = percentile(s.latencies, 0.50)
+		s.P95 = percentile(s.latencies, 0.95)
+		s.P99 = percentile(s.latencies, 0.99)
+	}
+
+	if !report.From.IsZero() {
+		report.Interval = perfIntervals[len(perfIntervals)-1]
+		for _, interval := range perfIntervals {
+			if report.To.Sub(report.From) < interval*perfBuckets {
+				report.Interval = interval
+				break
+			}
+		}
+		// Einzelne Ausreißer, z.B. ein falsch geparstes Jahr, würden sonst
+		// unzählige Balken ergeben; gezeigt werden höchstens die letzten
+		start := report.From.Truncate(report.Interval)
+		if limit := report.To.Truncate(report.Interval).Add(-(perfBuckets - 1) * report.Interval); start.Before(limit) {
+			start = limit
+		}
+		report.Buckets = make([]perfBucket, int(report.To.Sub(start)/report.Interval)+1)
+		for i := range report.Buckets {
+			report.Buckets[i].Start = start.Add(time.Duration(i) * report.Interval)
+		}
+		for _, r := range requests {
+			if r.entry.Timestamp.Before(start) {
+				continue
+			}
+			b := &report.Buckets[int(r.entry.Timestamp.Sub(start)/report.Interval)]
+			b.Requests++
+			if r.failed {
+				b.Errors++
+			}
+		}
+	}
+
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].latency > requests[j].latency })
+	for _, r := range requests {
+		if len(report.Slowest) == perfSlowest || !r.hasTime {
+			break
+		}
+		report.Slowest = append(report.Slowest, r)
+	}
+	return report
+}
+
+// percentile liefert den Wert nach dem Nearest-Rank-Verfahren aus sortierten Werten
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
+
+// Sortierungen der Endpunkte, mit der Sort-Taste umschaltbar
+var perfSorts = []string{"count", "p95", "errors", "bytes"}
+
+func (r perfReport) sortEndpoints(by string) []*endpointStats {
+	endpoints := append([]*endpointStats(nil), r.Endpoints...)
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		switch by {
+		case "p95":
+			return a.P95 > b.P95
+		case "errors":
+			return a.Errors > b.Errors
+		case "bytes":
+			return a.Bytes > b.Bytes
+		}
+		return a.Count > b.Count
+	})
+	return endpoints
+}
+
+// Zeichen für Verläufe, von leer bis voll
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline zeichnet Werte als Balken, relativ zum größten Wert
+func sparkline(values []float64) string {
+	peak := 0.0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if peak > 0 {
+			i = int(math.Round(v / peak * float64(len(sparkChars)-1)))
+		}
+		b.WriteRune(sparkChars[i])
+	}
+	return b.String()
+}
+
+// formatBytes gibt eine Datenmenge mit passender Einheit aus
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatInterval gibt ein Intervall der Verläufe aus, z.B. "5m" oder "6h"
+func formatInterval(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// formatLatency gibt eine Latenz in Millisekunden aus, "-" ohne Messwerte
+func formatLatency(d time.Duration, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.0fms", float64(d)/float64(time.Millisecond))
+}
+
+// renderPerformance erstellt die Zeilen der Auswertung für TUI und Kommandozeile
+func renderPerformance(cfg *Config, report perfReport, sortBy string) []string {
+	if report.Requests == 0 {
+		return []string{helpStyle.Render(tr("perf.none"))}
+	}
+
+	errorRate := float64(report.Errors) / float64(report.Requests) * 100
+	lines := []string{
+		helpStyle.Render(tr("perf.summary", report.Requests, report.Errors, errorRate, formatBytes(report.Bytes),
+			cfg.displayTime(report.From).Format("02.01.2006 15:04"), cfg.displayTime(report.To).Format("02.01.2006 15:04"))),
+		"",
+	}
+
+	requests := make([]float64, len(report.Buckets))
+	errors := make([]float64, len(report.Buckets))
+	peakRate, peakErrors := 0.0, 0.0
+	for i, b := range report.Buckets {
+		requests[i] = float64(b.Requests) / report.Interval.Minutes()
+		if b.Requests > 0 {
+			errors[i] = float64(b.Errors) / float64(b.Requests) * 100
+		}
+		peakRate = max(peakRate, requests[i])
+		peakErrors = max(peakErrors, errors[i])
+	}
+	lines = append(lines,
+		fmt.Sprintf("%-22s %s  %s", tr("perf.rate", formatInterval(report.Interval)), sparkline(requests), helpStyle.Render(tr("perf.peakRate", peakRate))),
+		fmt.Sprintf("%-22s %s  %s", tr("perf.errorRate"), parseErrorStyle.Render(sparkline(errors)), helpStyle.Render(tr("perf.peakErrors", peakErrors))),
+		"",
+		titleStyle.Render(tr("perf.endpoints", tr("perf.sort."+sortBy))),
+		helpStyle.Render(fmt.Sprintf(" %-40s %7s %6s %8s %8s %8s %10s", tr("perf.endpoint"), tr("perf.count"), tr("perf.errors"), "p50", "p95", "p99", tr("perf.bytes"))),
+	)
+	for i, s := range report.sortEndpoints(sortBy) {
+		if i == perfEndpoints {
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("… %d", len(report.Endpoints)-i)))
+			break
+		}
+		measured := len(s.latencies) > 0
+		line := fmt.Sprintf("%s %7d %5.1f%% %8s %8s %8s %10s",
+			fitCell(cfg.redactText(s.Endpoint), 40), s.Count, float64(s.Errors)/float64(s.Count)*100,
+			formatLatency(s.P50, measured), formatLatency(s.P95, measured), formatLatency(s.P99, measured), formatBytes(s.Bytes))
+		lines = append(lines, logLineStyle.Render(line))
+	}
+
+	if len(report.Slowest) > 0 {
+		lines = append(lines, "", titleStyle.Render(tr("perf.slowest")))
+		for _, r := range report.Slowest {
+			e := cfg.redact(r.entry)
+			ts := cfg.displayTime(e.Timestamp).Format("02.01.2006 15:04:05")
+			lines = append(lines, logLineStyle.Render(fmt.Sprintf("%s %8s %s", ts, formatLatency(r.latency, true), e.Message)))
+		}
+	} else {
+		lines = append(lines, "", helpStyle.Render(tr("perf.noLatency")))
+	}
+	return lines
+}
+
+// perfState hält die Auswertung des Performance-Bildschirms
+type perfState struct {
+	source LogConfig
+	report perfReport
+	sortBy int // Index in perfSorts
+	loaded bool
+}
+
+// perfMsg liefert das Ergebnis einer Auswertung an die TUI
+type perfMsg struct {
+	report perfReport
+	err    error
+}
+
+// startPerformance wertet die gewählte Quelle im Hintergrund aus
+func (m *model) startPerformance(cfg LogConfig) tea.Cmd {
+	m.screen = screenPerformance
+	m.perf = perfState{source: cfg, sortBy: m.perf.sortBy}
+	m.showError(tr("perf.running"))
+	return func() tea.Msg {
+		entries, err := readPerfSource(cfg)
+		return perfMsg{report: analyzePerformance(entries), err: err}
+	}
+}
+
+// readPerfSource liest eine Quelle unabhängig vom loglevel. Kommandos wie
+// "docker logs -f" und stdin enden nicht und werden abgelehnt.
+func readPerfSource(src LogConfig) ([]LogEntry, error) {
+	if src.Command != "" || src.isStdin() {
+		return nil, errorf("perf.stream", src.Name())
+	}
+	src.LogLevel = "debug"
+	entries, _, err := readEntries(src)
+	return entries, err
+}
+
+func (m model) updatePerformance(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.screen = screenList
+		return m, nil
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Reload):
+		return m, m.startPerformance(m.perf.source)
+	case key.Matches(msg, m.keys.Sort) && m.perf.loaded:
+		m.perf.sortBy = (m.perf.sortBy + 1) % len(perfSorts)
+		m.renderPerformance()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// showPerformance übernimmt das Ergebnis einer Auswertung
+func (m *model) showPerformance(msg perfMsg) {
+	if m.screen != screenPerformance {
+		return
+	}
+	if msg.err != nil {
+		m.showError(tr("perf.error", msg.err))
+		return
+	}
+	m.perf.report = msg.report
+	m.perf.loaded = true
+	m.viewport.GotoTop()
+	m.renderPerformance()
+}
+
+func (m *model) renderPerformance() {
+	lines := append([]string{titleStyle.Render(tr("perf.title", m.perf.source.Name()))},
+		renderPerformance(m.config, m.perf.report, perfSorts[m.perf.sortBy])...)
+	m.logs = lines
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// runPerformance wertet Access-Logs auf der Kommandozeile aus:
+// "analyzer perf [-sort count|p95|errors|bytes] <quelle>..."
+func runPerformance(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("perf", flag.ExitOnError)
+	sortBy := fs.String("sort", "count", "sort endpoints by count, p95, errors or bytes")
+	fs.Parse(args)
+
+	valid := false
+	for _, s := range perfSorts {
+		valid = valid || s == *sortBy
+	}
+	if !valid || fs.NArg() == 0 {
+		return errorf("perf.usage")
+	}
+
+	var all []LogEntry
+	for _, name := range fs.Args() {
+		entries, err := readPerfSource(cfg.lookupSource(name))
+		if err != nil {
+			return err
+		}
+		all = append(all, entries...)
+	}
+	for _, line := range renderPerformance(cfg, analyzePerformance(all), *sortBy) {
+		fmt.Println(line)
+	}
+	return nil
+}
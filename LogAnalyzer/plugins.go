@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version des Plugin-Protokolls
+const pluginProtocol = 1
+
+// Wartezeit auf die Antwort eines Plugins, danach wird es beendet
+const pluginTimeout = 5 * time.Second
+
+// Wartezeit, bevor ein beendetes Plugin neu gestartet wird. Bis dahin liefern
+// alle Zeilen den Fehler, statt für jede Zeile einen Start zu versuchen.
+const pluginRetryDelay = 10 * time.Second
+
+// PluginConfig beschreibt einen externen Parser. Quellen nutzen ihn über den
+// Namen als type; bei der automatischen Erkennung werden Plugins nicht
+// berücksichtigt, damit nicht für jede Quelle alle Plugins starten.
+//
+// Protokoll: das Plugin erhält zuerst {"protocol":1} und antwortet mit seiner
+// Version im selben Format. Danach erhält es je eine Rohzeile und antwortet
+// je Zeile mit einem LogEntry als JSON oder mit {"error":"..."}.
+type PluginConfig struct {
+	Name    string `yaml:"name"`
+	Command string `yaml:"command"` // z.B. "python3 parsers/haproxy.py"
+}
+
+// pluginHello ist die erste Nachricht in beide Richtungen
+type pluginHello struct {
+	Protocol int `json:"protocol"`
+}
+
+// pluginReply ist die Antwort auf eine Zeile
+type pluginReply struct {
+	LogEntry
+	Error string `json:"error,omitempty"`
+}
+
+// pluginProcess ist ein laufendes Plugin. Es wird von allen Quellen geteilt
+// und beantwortet eine Zeile nach der anderen.
+type pluginProcess struct {
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stdin   io.WriteCloser
+	replies chan []byte
+	stderr  *bytes.Buffer
+	done    chan struct{}
+	err     error // Grund für das Ende des Prozesses
+	ended   time.Time
+}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   = map[string]*pluginProcess{}
+)
+
+// startPlugin startet ein Plugin und prüft die Protokollversion
+func startPlugin(cfg PluginConfig) (*pluginProcess, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := shellCommand(ctx, cfg.Command)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// stderr würde die TUI überschreiben und wird nur für Fehlermeldungen gesammelt
+	p := &pluginProcess{
+		cancel:  cancel,
+		stdin:   stdin,
+		replies: make(chan []byte),
+		stderr:  &bytes.Buffer{},
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = p.stderr
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, errorf("plugin.start", cfg.Name, err)
+	}
+
+	go func() {
+		scanner := newLineScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case p.replies <- bytes.Clone(scanner.Bytes()):
+			case <-ctx.Done():
+			}
+		}
+		err := cmd.Wait()
+		if msg := strings.TrimSpace(p.stderr.String()); msg != "" {
+			err = fmt.Errorf("%v: %s", err, msg)
+		}
+		p.err = errorf("plugin.exited", cfg.Name, err)
+		p.ended = time.Now()
+		close(p.done)
+	}()
+
+	hello, _ := json.Marshal(pluginHello{Protocol: pluginProtocol})
+	reply, err := p.request(hello)
+	if err != nil {
+		p.stop()
+		return nil, errorf("plugin.handshake", cfg.Name, err)
+	}
+	var answer pluginHello
+	if err := json.Unmarshal(reply, &answer); err != nil {
+		p.stop()
+		return nil, errorf("plugin.handshake", cfg.Name, err)
+	}
+	if answer.Protocol != pluginProtocol {
+		p.stop()
+		return nil, errorf("plugin.version", cfg.Name, answer.Protocol, pluginProtocol)
+	}
+	return p, nil
+}
+
+// request sendet eine Zeile und wartet auf die Antwort
+func (p *pluginProcess) request(line []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return nil, p.exitError(err)
+	}
+	select {
+	case reply := <-p.replies:
+		return reply, nil
+	case <-p.done:
+		return nil, p.exitError(io.ErrUnexpectedEOF)
+	case <-time.After(pluginTimeout):
+		p.cancel()
+		return nil, errorf("plugin.timeout", pluginTimeout)
+	}
+}
+
+// exitError liefert den Grund für das Ende des Prozesses, falls bekannt
+func (p *pluginProcess) exitError(err error) error {
+	select {
+	case <-p.done:
+		if p.err != nil {
+			return p.err
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+	return err
+}
+
+func (p *pluginProcess) stop() {
+	p.stdin.Close()
+	p.cancel()
+}
+
+// runningPlugin liefert den laufenden Prozess eines Plugins und startet ihn
+// bei Bedarf (erneut)
+func runningPlugin(cfg PluginConfig) (*pluginProcess, error) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	if p, ok := plugins[cfg.Name]; ok {
+		select {
+		case <-p.done:
+			if time.Since(p.ended) < pluginRetryDelay {
+				return nil, p.err
+			}
+		default:
+			return p, nil
+		}
+	}
+	p, err := startPlugin(cfg)
+	if err != nil {
+		// Fehlgeschlagenen Start wie ein beendetes Plugin behandeln
+		p = &pluginProcess{done: make(chan struct{}), err: err, ended: time.Now()}
+		close(p.done)
+		plugins[cfg.Name] = p
+		return nil, err
+	}
+	plugins[cfg.Name] = p
+	return p, nil
+}
+
+// PluginParser parst Zeilen mit einem externen Plugin
+type PluginParser struct {
+	cfg PluginConfig
+}
+
+func (p *PluginParser) Parse(line string) (LogEntry, error) {
+	proc, err := runningPlugin(p.cfg)
+	if err != nil {
+		return LogEntry{}, err
+	}
+	data, err := proc.request([]byte(line))
+	if err != nil {
+		return LogEntry{}, err
+	}
+
+	var reply pluginReply
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return LogEntry{}, errorf("plugin.reply", p.cfg.Name, err)
+	}
+	if reply.Error != "" {
+		return LogEntry{}, errorf("plugin.parse", p.cfg.Name, reply.Error)
+	}
+	entry := reply.LogEntry
+	entry.Severity = strings.ToLower(entry.Severity)
+	if entry.Source == "" {
+		entry.Source = p.cfg.Name
+	}
+	return entry, nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// parsedLine ist das Ergebnis des Parsers für eine Zeile
+type parsedLine struct {
+	line  string
+	entry LogEntry
+	err   error
+}
+
+// entryReader parst die Zeilen einer Quelle. Ist kein Typ konfiguriert, werden
+// die ersten Zeilen gesammelt, bis das Format erkannt werden kann.
+type entryReader struct {
+	cfg     LogConfig
+	parser  Parser
+	typ     string
+	pending []string
+	err     error // Format konnte nicht erkannt werden
+	parsed  int
+	failed  int
+}
+
+func newEntryReader(cfg LogConfig) (*entryReader, error) {
+	r := &entryReader{cfg: cfg}
+	if !cfg.autoDetect() {
+		parser, ok := newParsers(cfg)[cfg.Type]
+		if !ok {
+			return nil, errorf("parser.missing", cfg.Type)
+		}
+		r.parser = parser
+		r.typ = cfg.Type
+	}
+	return r, nil
+}
+
+// add verarbeitet eine Zeile. Solange das Format noch nicht erkannt ist, wird
+// nichts geliefert; danach alle gesammelten Zeilen auf einmal.
+func (r *entryReader) add(line string) []parsedLine {
+	if strings.TrimSpace(line) == "" || r.err != nil {
+		return nil
+	}
+	if r.parser == nil {
+		r.pending = append(r.pending, line)
+		if len(r.pending) < detectSampleLines {
+			return nil
+		}
+		return r.flush()
+	}
+	if p, ok := r.parse(line); ok {
+		return []parsedLine{p}
+	}
+	return nil
+}
+
+// flush erkennt das Format mit den bisher gesammelten Zeilen, z.B. wenn die
+// Quelle weniger Zeilen als für die Erkennung nötig enthält
+func (r *entryReader) flush() []parsedLine {
+	if r.parser != nil || r.err != nil || len(r.pending) == 0 {
+		return nil
+	}
+	if !r.detect(r.pending) {
+		r.pending = nil
+		return nil
+	}
+
+	results := make([]parsedLine, 0, len(r.pending))
+	for _, line := range r.pending {
+		if p, ok := r.parse(line); ok {
+			results = append(results, p)
+		}
+	}
+	r.pending = nil
+	return results
+}
+
+// detect wählt den Parser anhand der Beispielzeilen
+func (r *entryReader) detect(sample []string) bool {
+	parsers := newParsers(r.cfg)
+	typ, _ := detectParser(parsers, sample)
+	if typ == "" {
+		r.err = errorf("parser.undetected", r.cfg.Name())
+		return false
+	}
+	// Neu erstellen, damit keine Teilzeilen aus der Erkennung übrig bleiben
+	r.parser = newParsers(r.cfg)[typ]
+	r.typ = typ
+	return true
+}
+
+// parse liefert das Ergebnis einer Zeile; Teilzeilen ergeben noch keines
+func (r *entryReader) parse(line string) (parsedLine, bool) {
+	entry, err := r.parser.Parse(line)
+	switch {
+	case err == errPartialLine:
+		return parsedLine{}, false
+	case err != nil:
+		r.failed++
+	default:
+		entry = r.complete(entry)
+	}
+	return parsedLine{line: line, entry: entry, err: err}, true
+}
+
+// complete zählt einen geparsten Eintrag und wendet Severity-Regeln und
+// Anreicherung an. Die Regeln sehen nur die Felder des Parsers, damit das
+// Level nicht von DNS-Antworten oder GeoIP-Datenbanken abhängt.
+func (r *entryReader) complete(entry LogEntry) LogEntry {
+	r.parsed++
+	entry = applySeverityRules(r.cfg.severityRules, entry)
+	return r.cfg.enricher.enrich(entry)
+}
+
+// partialParser hält Teilzeilen zurück, bis sie vollständig sind
+type partialParser interface {
+	flushPartial() []LogEntry
+	hasPartial() bool
+}
+
+// holding meldet, ob der Reader noch Zeilen zurückhält, für die kein
+// Ergebnis geliefert wurde
+func (r *entryReader) holding() bool {
+	if len(r.pending) > 0 {
+		return true
+	}
+	p, ok := r.parser.(partialParser)
+	return ok && p.hasPartial()
+}
+
+// idle liefert bei einer Pause oder am Ende der Quelle alles, was der Reader
+// noch zurückhält: Zeilen vor der Erkennung und Teilzeilen von Containern
+func (r *entryReader) idle() []parsedLine {
+	results := r.flush()
+	if p, ok := r.parser.(partialParser); ok {
+		for _, e := range p.flushPartial() {
+			results = append(results, parsedLine{entry: r.complete(e)})
+		}
+	}
+	return results
+}
+
+// Intervall, in dem verfolgte Dateien auf neue Zeilen geprüft werden
+const followInterval = time.Second
+
+// followSource liest eine Quelle von Beginn an (bei Dateien optional ab dem
+// Ende) und verfolgt danach neue Zeilen, bis ctx beendet wird oder die Quelle
+// endet. idle wird aufgerufen, sobald alle vorhandenen Zeilen gelesen wurden.
+func followSource(ctx context.Context, cfg LogConfig, fromEnd bool, handle func(line string), idle func()) error {
+	if cfg.Command == "" && !cfg.isStdin() {
+		return followFile(ctx, cfg.Path, fromEnd, handle, idle)
+	}
+
+	stream, err := openStream(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Command != "" {
+		defer stream.Close()
+	}
+
+	pos := 0
+	for {
+		lines, next, done, err, notify := stream.since(pos)
+		for _, line := range lines {
+			handle(line)
+		}
+		pos = next
+		if done {
+			return err
+		}
+		idle()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+		}
+	}
+}
+
+// followFile liest eine Datei wie "tail -F": nach dem Ende wird auf neue Zeilen
+// gewartet, bei Rotation oder Kürzung wird die Datei neu von vorne gelesen
+func followFile(ctx context.Context, path string, fromEnd bool, handle func(line string), idle func()) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	if fromEnd {
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+
+	reader := bufio.NewReader(file)
+	partial := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err == nil {
+			handle(strings.TrimRight(partial+line, "\r\n"))
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		// Unvollständige Zeile aufheben, bis der Rest geschrieben wurde
+		partial += line
+		idle()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(followInterval):
+		}
+
+		if reopened, err := reopenIfRotated(file, path); err != nil {
+			// Datei ist während der Rotation kurz nicht vorhanden
+			continue
+		} else if reopened != nil {
+			file.Close()
+			file = reopened
+			reader.Reset(file)
+			partial = ""
+		}
+	}
+}
+
+// reopenIfRotated liefert eine neu geöffnete Datei, wenn path inzwischen auf eine
+// andere Datei zeigt oder die aktuelle Datei gekürzt wurde
+func reopenIfRotated(file *os.File, path string) (*os.File, error) {
+	current, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if os.SameFile(current, opened) && current.Size() >= offset {
+		return nil, nil
+	}
+	return os.Open(path)
+}
+
+// tailEntries verfolgt eine Quelle und übergibt die Parse-Ergebnisse an handle,
+// bis ctx beendet wird oder die Quelle endet
+func tailEntries(ctx context.Context, cfg LogConfig, fromEnd bool, handle func(r *entryReader, results []parsedLine)) error {
+	reader, err := newEntryReader(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Ab dem Ende gelesene Dateien anhand ihres Anfangs erkennen
+	if fromEnd && reader.parser == nil && cfg.Command == "" && !cfg.isStdin() {
+		if sample, err := sampleFile(cfg.Path, detectSampleLines); err == nil && len(sample) > 0 && !reader.detect(sample) {
+			return reader.err
+		}
+	}
+
+	// Bei weniger Zeilen als für die Erkennung nötig mit den vorhandenen erkennen
+	err = followSource(ctx, cfg, fromEnd, func(line string) {
+		handle(reader, reader.add(line))
+	}, func() {
+		if results := reader.idle(); len(results) > 0 {
+			handle(reader, results)
+		}
+	})
+	handle(reader, reader.idle())
+	if err == nil {
+		err = reader.err
+	}
+	return err
+}
+
+// readEntries liest eine Quelle einmal vollständig, ohne auf neue Zeilen zu
+// warten. Einträge unterhalb des Levels der Quelle werden verworfen.
+func readEntries(cfg LogConfig) ([]LogEntry, *entryReader, error) {
+	reader, err := newEntryReader(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []LogEntry
+	collect := func(results []parsedLine) {
+		for _, p := range results {
+			if p.err == nil && shouldLog(cfg.LogLevel, p.entry.Severity) {
+				entries = append(entries, p.entry)
+			}
+		}
+	}
+
+	if cfg.Command != "" || cfg.isStdin() {
+		stream, err := openStream(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.Command != "" {
+			defer stream.Close()
+		}
+		// Auf das Ende des Kommandos bzw. von stdin warten
+		pos := 0
+		for {
+			lines, next, done, err, notify := stream.since(pos)
+			for _, line := range lines {
+				collect(reader.add(line))
+			}
+			pos = next
+			if done {
+				if err != nil {
+					return nil, nil, err
+				}
+				break
+			}
+			<-notify
+		}
+	} else {
+		if cfg.indexed() {
+			if entries, reader, err := indexedEntries(cfg); err == nil {
+				return entries, reader, nil
+			}
+		}
+		file, err := os.Open(cfg.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+
+		scanner := newLineScanner(file)
+		for scanner.Scan() {
+			collect(reader.add(scanner.Text()))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	collect(reader.idle())
+	if reader.err != nil {
+		return nil, nil, reader.err
+	}
+	return entries, reader, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// RedactionConfig beschreibt, welche personenbezogenen Daten vor der Anzeige
+// und beim Export ersetzt werden
+type RedactionConfig struct {
+	Mode      string       `yaml:"mode"`      // mask (Standard) oder pseudonymize
+	Key       string       `yaml:"key"`       // Schlüssel für die Pseudonyme
+	Detectors []string     `yaml:"detectors"` // ip, email, token
+	Fields    []string     `yaml:"fields"`    // Metadata-Felder, die vollständig ersetzt werden
+	Rules     []RedactRule `yaml:"rules"`
+}
+
+// RedactRule ist ein eigener Ausdruck. Mit einer Gruppe wird nur diese ersetzt.
+type RedactRule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// redactPattern ersetzt Treffer eines Ausdrucks bzw. dessen erste Gruppe
+type redactPattern struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(s string, start, end int) bool // optionale Prüfung eines Treffers
+}
+
+// Eingebaute Erkennungen
+var redactDetectors = map[string][]redactPattern{
+	"ip": {
+		{name: "ip", re: regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`), valid: isIPv4},
+		{name: "ip", re: regexp.MustCompile(`(?:[0-9a-fA-F]{0,4}:){2,7}[0-9a-fA-F]{0,4}`), valid: isIPv6},
+	},
+	"email": {
+		{name: "email", re: regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)},
+	},
+	"token": {
+		{name: "token", re: regexp.MustCompile(`(?i)\bbearer\s+([\w\-.~+/]+=*)`)},
+		{name: "token", re: regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]+`)},
+		{name: "token", re: regexp.MustCompile(`(?i)\b(?:token|access_token|api_key|apikey|password|passwd|secret)=([^&\s"]+)`)},
+	},
+}
+
+func isIPv4(s string, start, end int) bool {
+	return net.ParseIP(s[start:end]) != nil
+}
+
+// isIPv6 schließt Uhrzeiten und Bezeichner wie "Cache::add" aus: der Treffer
+// muss frei stehen und mindestens zwei Gruppen enthalten
+func isIPv6(s string, start, end int) bool {
+	if (start > 0 && isWordByte(s[start-1])) || (end < len(s) && isWordByte(s[end])) {
+		return false
+	}
+	value := s[start:end]
+	groups := 0
+	for _, g := range strings.Split(value, ":") {
+		if g != "" {
+			groups++
+		}
+	}
+	return groups >= 2 && net.ParseIP(value) != nil
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b == '.' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// redactor wendet die Regeln einer RedactionConfig an
+type redactor struct {
+	pseudonymize bool
+	key          []byte
+	patterns     []redactPattern
+	fields       map[string]bool
+}
+
+// newRedactor prüft die Konfiguration; ohne Regeln wird nil geliefert
+func newRedactor(cfg RedactionConfig) (*redactor, error) {
+	if len(cfg.Detectors) == 0 && len(cfg.Fields) == 0 && len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+	r := &redactor{key: []byte(cfg.Key), fields: map[string]bool{}}
+	switch cfg.Mode {
+	case "", "mask":
+	case "pseudonymize":
+		r.pseudonymize = true
+	default:
+		return nil, errorf("redact.mode", cfg.Mode)
+	}
+
+	for _, name := range cfg.Detectors {
+		patterns, ok := redactDetectors[name]
+		if !ok {
+			return nil, errorf("redact.detector", name)
+		}
+		r.patterns = append(r.patterns, patterns...)
+	}
+	for _, rule := range cfg.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, errorf("redact.rule", rule.Name, err)
+		}
+		name := rule.Name
+		if name == "" {
+			name = "redacted"
+		}
+		r.patterns = append(r.patterns, redactPattern{name: name, re: re})
+	}
+	for _, field := range cfg.Fields {
+		r.fields[field] = true
+	}
+	return r, nil
+}
+
+// replacement liefert den Ersatz für einen Wert: "<name>" oder, beim
+// Pseudonymisieren, "<name:hash>". Gleiche Werte ergeben gleiche Hashes.
+func (r *redactor) replacement(name, value string) string {
+	if !r.pseudonymize {
+		return "<" + name + ">"
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return "<" + name + ":" + hex.EncodeToString(mac.Sum(nil))[:8] + ">"
+}
+
+// text ersetzt alle Treffer der Regeln in s
+func (r *redactor) text(s string) string {
+	for _, p := range r.patterns {
+		s = p.apply(s, r)
+	}
+	return s
+}
+
+func (p redactPattern) apply(s string, r *redactor) string {
+	matches := p.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// Mit Gruppe nur deren Inhalt ersetzen
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		value := s[start:end]
+		if value == "" || (p.valid != nil && !p.valid(s, start, end)) {
+			continue
+		}
+		out = append(out, s[last:start]...)
+		out = append(out, r.replacement(p.name, value)...)
+		last = end
+	}
+	return string(append(out, s[last:]...))
+}
+
+// entry liefert eine Kopie des Eintrags mit ersetzten Daten
+func (r *redactor) entry(e LogEntry) LogEntry {
+	e.Message = r.text(e.Message)
+	if e.Metadata != nil {
+		metadata := make(map[string]string, len(e.Metadata))
+		for k, v := range e.Metadata {
+			metadata[k] = r.field(k, v)
+		}
+		e.Metadata = metadata
+	}
+	return e
+}
+
+// field ersetzt den Wert eines Metadata-Feldes
+func (r *redactor) field(name, value string) string {
+	switch {
+	case value == "":
+		return value
+	case r.fields[name]:
+		return r.replacement(name, value)
+	}
+	return r.text(value)
+}
+
+// redact ersetzt personenbezogene Daten eines Eintrags gemäß der Konfiguration
+func (c *Config) redact(e LogEntry) LogEntry {
+	if c.redactor == nil {
+		return e
+	}
+	return c.redactor.entry(e)
+}
+
+// redactText ersetzt personenbezogene Daten in einem Text, z.B. einer Rohzeile
+func (c *Config) redactText(s string) string {
+	if c.redactor == nil {
+		return s
+	}
+	return c.redactor.text(s)
+}
+
+// redactField ersetzt den Wert eines Metadata-Feldes, z.B. für Metrik-Labels
+func (c *Config) redactField(field, value string) string {
+	if c.redactor == nil {
+		return value
+	}
+	return c.redactor.field(field, value)
+}
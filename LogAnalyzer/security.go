@@ -0,0 +1,518 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// finding ist ein erkannter Angriff mit den zugehörigen Einträgen
+type finding struct {
+	Kind     string // bruteforce, stuffing, notfound, scanner, sqli, traversal, useragent
+	Severity string // info, warn oder error
+	IP       string
+	Detail   string // z.B. Benutzer, Pfade oder Werkzeug
+	Entries  []LogEntry
+}
+
+// Schwellen für Serien innerhalb eines Zeitfensters
+const (
+	loginFailThreshold = 5
+	loginFailWindow    = 10 * time.Minute
+	stuffingIPs        = 3 // verschiedene IPs je Benutzer
+	notFoundThreshold  = 20
+	notFoundWindow     = 5 * time.Minute
+	maxDetailValues    = 3
+)
+
+var (
+	loginFailedRegex = regexp.MustCompile(`(?i)login failed|bruteforce attempt`)
+	loginUserRegex   = regexp.MustCompile(`Login failed: '([^']*)'`)
+	remoteIPRegex    = regexp.MustCompile(`Remote IP: '([^']+)'|attempt from "([^"]+)"`)
+
+	sqliRegex      = regexp.MustCompile(`(?i)union(\s|\+|/\*.*?\*/)+(all(\s|\+)+)?select|'\s*or\s*'?\d+'?\s*=\s*'?\d+|\bor\s+1\s*=\s*1\b|sleep\(\s*\d+\s*\)|benchmark\(|information_schema|;\s*(drop|insert|update|delete)\s|--\s*$`)
+	traversalRegex = regexp.MustCompile(`(?i)\.\./|\.\.\\|/etc/(passwd|shadow)|win\.ini|boot\.ini`)
+)
+
+// Pfade, die typischerweise von Schwachstellen-Scannern abgefragt werden
+var scannerPaths = []string{
+	"/wp-login.php", "/wp-admin", "/xmlrpc.php", "/wp-content/plugins",
+	"/.env", "/.git/", "/.aws/", "/.ssh/", "/.ds_store",
+	"/phpmyadmin", "/pma/", "/myadmin", "/adminer.php",
+	"/cgi-bin/", "/boaform", "/hnap1", "/vendor/phpunit",
+	"/actuator", "/solr/", "/console/", "/manager/html",
+	"/server-status", "/config.php.bak", "/backup.sql", "/shell.php",
+}
+
+// User-Agents bekannter Angriffs- und Scan-Werkzeuge (warn) bzw. von
+// Skripten, die im Browser-Verkehr auffallen (info)
+var (
+	scannerAgents = []string{"sqlmap", "nikto", "nmap", "masscan", "zgrab", "nuclei", "wpscan", "dirbuster", "gobuster", "ffuf", "acunetix", "nessus", "openvas", "hydra", "censysinspect"}
+	scriptAgents  = []string{"curl/", "wget/", "python-requests", "python-urllib", "go-http-client", "libwww-perl", "java/", "okhttp"}
+)
+
+// detectFindings durchsucht die Einträge nach Angriffsmustern
+func detectFindings(entries []LogEntry) []finding {
+	var findings []finding
+	findings = append(findings, detectLoginFailures(entries)...)
+	findings = append(findings, detectNotFoundBursts(entries)...)
+	findings = append(findings, detectRequests(entries)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if levelOrder[a.Severity] != levelOrder[b.Severity] {
+			return levelOrder[a.Severity] > levelOrder[b.Severity]
+		}
+		if len(a.Entries) != len(b.Entries) {
+			return len(a.Entries) > len(b.Entries)
+		}
+		return a.IP < b.IP
+	})
+	return findings
+}
+
+// entryIP liefert die Adresse des Clients eines Eintrags
+func entryIP(e LogEntry) string {
+	if ip := e.Metadata["remoteAddr"]; ip != "" {
+		return ip
+	}
+	if m := remoteIPRegex.FindStringSubmatch(e.Message); m != nil {
+		return m[1] + m[2]
+	}
+	return ""
+}
+
+// detectLoginFailures findet Serien fehlgeschlagener Anmeldungen je IP sowie
+// Benutzer, die von vielen IPs aus angegriffen werden
+func detectLoginFailures(entries []LogEntry) []finding {
+	byIP := map[string][]LogEntry{}
+	byUser := map[string][]LogEntry{}
+	for _, e := range entries {
+		if !loginFailedRegex.MatchString(e.Message) {
+			continue
+		}
+		if ip := entryIP(e); ip != "" {
+			byIP[ip] = append(byIP[ip], e)
+		}
+		if m := loginUserRegex.FindStringSubmatch(e.Message); m != nil && m[1] != "" {
+			byUser[m[1]] = append(byUser[m[1]], e)
+		}
+	}
+
+	var findings []finding
+	for ip, failed := range byIP {
+		if maxInWindow(failed, loginFailWindow) < loginFailThreshold {
+			continue
+		}
+		var users []string
+		for _, e := range failed {
+			if m := loginUserRegex.FindStringSubmatch(e.Message); m != nil {
+				users = append(users, m[1])
+			}
+		}
+		findings = append(findings, finding{Kind: "bruteforce", Severity: "error", IP: ip, Detail: joinDistinct(users), Entries: failed})
+	}
+	for user, failed := range byUser {
+		ips := map[string]bool{}
+		for _, e := range failed {
+			ips[entryIP(e)] = true
+		}
+		if len(ips) < stuffingIPs {
+			continue
+		}
+		findings = append(findings, finding{Kind: "stuffing", Severity: "warn", Detail: user, Entries: failed})
+	}
+	return findings
+}
+
+// detectNotFoundBursts findet IPs mit vielen 404-Antworten in kurzer Zeit
+func detectNotFoundBursts(entries []LogEntry) []finding {
+	byIP := map[string][]LogEntry{}
+	for _, e := range entries {
+		if e.Metadata["status"] == "404" {
+			ip := entryIP(e)
+			byIP[ip] = append(byIP[ip], e)
+		}
+	}
+
+	var findings []finding
+	for ip, notFound := range byIP {
+		if maxInWindow(notFound, notFoundWindow) < notFoundThreshold {
+			continue
+		}
+		var urls []string
+		for _, e := range notFound {
+			urls = append(urls, e.Metadata["url"])
+		}
+		findings = append(findings, finding{Kind: "notfound", Severity: "warn", IP: ip, Detail: joinDistinct(urls), Entries: notFound})
+	}
+	return findings
+}
+
+// detectRequests prüft URL und User-Agent jeder Anfrage und fasst die
+// Treffer je IP und Art zusammen
+func detectRequests(entries []LogEntry) []finding {
+	type group struct {
+		kind, severity, ip string
+	}
+	groups := map[group]*finding{}
+	details := map[group][]string{}
+	var order []group
+	add := func(kind, severity, ip, detail string, e LogEntry) {
+		g := group{kind, severity, ip}
+		f, ok := groups[g]
+		if !ok {
+			f = &finding{Kind: kind, Severity: severity, IP: ip}
+			groups[g] = f
+			order = append(order, g)
+		}
+		details[g] = append(details[g], detail)
+		f.Entries = append(f.Entries, e)
+	}
+
+	for _, e := range entries {
+		ip := entryIP(e)
+		if raw := e.Metadata["url"]; raw != "" {
+			decoded := decodeURL(raw)
+			lower := strings.ToLower(decoded)
+			for _, p := range scannerPaths {
+				if strings.Contains(lower, p) {
+					add("scanner", "warn", ip, p, e)
+					break
+				}
+			}
+			if m := sqliRegex.FindString(decoded); m != "" {
+				add("sqli", "error", ip, m, e)
+			}
+			if m := traversalRegex.FindString(decoded); m != "" {
+				add("traversal", "error", ip, m, e)
+			}
+		}
+		if agent := strings.ToLower(e.Metadata["userAgent"]); agent != "" {
+			if tool := matchAny(agent, scannerAgents); tool != "" {
+				add("useragent", "warn", ip, tool, e)
+			} else if tool := matchAny(agent, scriptAgents); tool != "" {
+				add("useragent", "info", ip, strings.TrimSuffix(tool, "/"), e)
+			}
+		}
+	}
+
+	findings := make([]finding, 0, len(order))
+	for _, g := range order {
+		f := groups[g]
+		f.Detail = joinDistinct(details[g])
+		findings = append(findings, *f)
+	}
+	return findings
+}
+
+// decodeURL dekodiert Prozent-Kodierung, auch doppelte
+func decodeURL(s string) string {
+	for i := 0; i < 2; i++ {
+		decoded, err := url.QueryUnescape(s)
+		if err != nil || decoded == s {
+			break
+		}
+		s = decoded
+	}
+	return s
+}
+
+func matchAny(s string, candidates []string) string {
+	for _, c := range candidates {
+		if strings.Contains(s, c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// maxInWindow liefert die größte Anzahl Einträge innerhalb eines Zeitfensters
+func maxInWindow(entries []LogEntry, window time.Duration) int {
+	times := make([]time.Time, len(entries))
+	for i, e := range entries {
+		times[i] = e.Timestamp
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	best, start := 0, 0
+	for end := range times {
+		for times[end].Sub(times[start]) > window {
+			start++
+		}
+		if n := end - start + 1; n > best {
+			best = n
+		}
+	}
+	return best
+}
+
+// joinDistinct verbindet die ersten verschiedenen Werte
+func joinDistinct(values []string) string {
+	seen := map[string]bool{}
+	var distinct []string
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		distinct = append(distinct, v)
+	}
+	if len(distinct) > maxDetailValues {
+		return strings.Join(distinct[:maxDetailValues], ", ") + ", …"
+	}
+	return strings.Join(distinct, ", ")
+}
+
+// banList schlägt die IPs aller Funde ab Level warn zur Sperrung vor, die
+// auffälligsten zuerst
+func banList(findings []finding) []string {
+	counts := map[string]int{}
+	for _, f := range findings {
+		if f.IP == "" || levelOrder[f.Severity] < levelOrder["warn"] {
+			continue
+		}
+		counts[f.IP] += len(f.Entries)
+	}
+	ips := make([]string, 0, len(counts))
+	for ip := range counts {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		if counts[ips[i]] != counts[ips[j]] {
+			return counts[ips[i]] > counts[ips[j]]
+		}
+		return ips[i] < ips[j]
+	})
+	return ips
+}
+
+// securityScan liest die Quellen vollständig, unabhängig vom loglevel.
+// Kommandos und stdin enden nicht; sie werden übersprungen und ihre Namen
+// geliefert.
+func securityScan(sources []LogConfig) ([]finding, int, []string, error) {
+	var all []LogEntry
+	var skipped []string
+	for _, src := range sources {
+		if src.Command != "" || src.isStdin() {
+			skipped = append(skipped, src.Name())
+			continue
+		}
+		src.LogLevel = "debug"
+		entries, _, err := readEntries(src)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		all = append(all, entries...)
+	}
+	return detectFindings(all), len(all), skipped, nil
+}
+
+// securityState hält die Funde des Sicherheits-Bildschirms
+type securityState struct {
+	source   LogConfig
+	findings []finding
+	total    int      // Anzahl untersuchter Einträge
+	skipped  []string // Kommandos und stdin
+	selected int
+	expanded map[int]bool
+	loaded   bool
+}
+
+// securityMsg liefert das Ergebnis einer Untersuchung an die TUI
+type securityMsg struct {
+	findings []finding
+	total    int
+	skipped  []string
+	err      error
+}
+
+// Maximale Anzahl angezeigter Einträge je aufgeklapptem Fund
+const maxFindingEntries = 50
+
+// startSecurity untersucht die gewählte Quelle im Hintergrund
+func (m *model) startSecurity(cfg LogConfig) tea.Cmd {
+	m.screen = screenSecurity
+	m.security = securityState{source: cfg, expanded: map[int]bool{}}
+	m.showError(tr("security.running"))
+	return func() tea.Msg {
+		findings, total, skipped, err := securityScan([]LogConfig{cfg})
+		return securityMsg{findings: findings, total: total, skipped: skipped, err: err}
+	}
+}
+
+func (m model) updateSecurity(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := &m.security
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.screen = screenList
+		return m, nil
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Reload):
+		return m, m.startSecurity(s.source)
+	case !s.loaded:
+		return m, nil
+	case key.Matches(msg, m.keys.Up):
+		if s.selected > 0 {
+			s.selected--
+		}
+		m.renderSecurity()
+		return m, nil
+	case key.Matches(msg, m.keys.Down):
+		if s.selected < len(s.findings)-1 {
+			s.selected++
+		}
+		m.renderSecurity()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		s.expanded[s.selected] = !s.expanded[s.selected]
+		m.renderSecurity()
+		return m, nil
+	case key.Matches(msg, m.keys.Activity):
+		// Zeitleiste der IP des gewählten Funds
+		if s.selected < len(s.findings) && s.findings[s.selected].IP != "" {
+			return m, m.startSessions(&actor{Field: "remoteAddr", Value: s.findings[s.selected].IP})
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// showSecurity übernimmt das Ergebnis einer Untersuchung
+func (m *model) showSecurity(msg securityMsg) {
+	if m.screen != screenSecurity {
+		return
+	}
+	if msg.err != nil {
+		m.showError(tr("security.error", msg.err))
+		return
+	}
+	m.security.findings = msg.findings
+	m.security.total = msg.total
+	m.security.skipped = msg.skipped
+	m.security.loaded = true
+	m.viewport.GotoTop()
+	m.renderSecurity()
+}
+
+// renderSecurity zeigt die Funde, den gewählten hervorgehoben, aufgeklappte
+// mit ihren Einträgen, und darunter die Sperrliste
+func (m *model) renderSecurity() {
+	s := m.security
+	lines := []string{
+		titleStyle.Render(tr("security.title", s.source.Name())),
+		helpStyle.Render(tr("security.summary", s.total, len(s.findings))),
+		"",
+	}
+	for _, name := range s.skipped {
+		lines = append(lines, parseErrorStyle.Render(tr("security.skipped", name)))
+	}
+	if len(s.findings) == 0 {
+		lines = append(lines, helpStyle.Render(tr("security.none")))
+	}
+
+	selectedLine := 0
+	for i, f := range s.findings {
+		ip := f.IP
+		if ip == "" {
+			ip = "-"
+		}
+		severityStyle := lipgloss.NewStyle().Foreground(severityColor(f.Severity)).Bold(true)
+		line := fmt.Sprintf("%s %-22s %-16s %5d× %s",
+			severityStyle.Render(fmt.Sprintf("%-5s", f.Severity)),
+			tr("security.kind."+f.Kind),
+			m.config.redactField("remoteAddr", ip),
+			len(f.Entries),
+			m.config.redactText(f.Detail))
+		if i == s.selected {
+			selectedLine = len(lines)
+			lines = append(lines, selectedStyle.Render("▸ "+line))
+		} else {
+			lines = append(lines, logLineStyle.Render(" "+line))
+		}
+
+		if !s.expanded[i] {
+			continue
+		}
+		for j, e := range f.Entries {
+			if j == maxFindingEntries {
+				lines = append(lines, helpStyle.Render(fmt.Sprintf("      … %d", len(f.Entries)-j)))
+				break
+			}
+			e = m.config.redact(e)
+			ts := m.config.displayTime(e.Timestamp).Format("02.01.2006 15:04:05")
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("      %s %s", ts, e.Message)))
+		}
+	}
+
+	if ips := banList(s.findings); len(ips) > 0 {
+		lines = append(lines, "", titleStyle.Render(tr("security.banList")))
+		for _, ip := range ips {
+			lines = append(lines, logLineStyle.Render(m.config.redactField("remoteAddr", ip)))
+		}
+	}
+
+	m.logs = lines
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	// Den gewählten Fund sichtbar halten
+	if selectedLine < m.viewport.YOffset {
+		m.viewport.SetYOffset(selectedLine)
+	} else if selectedLine >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(selectedLine - m.viewport.Height + 1)
+	}
+}
+
+// runSecurity untersucht Quellen auf der Kommandozeile:
+// "analyzer security [-ips] <quelle>..."
+func runSecurity(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("security", flag.ExitOnError)
+	ipsOnly := fs.Bool("ips", false, "print only the suggested IP ban list")
+	fs.Parse(args)
+
+	sources := cfg.Logs
+	if fs.NArg() > 0 {
+		sources = nil
+		for _, name := range fs.Args() {
+			sources = append(sources, cfg.lookupSource(name))
+		}
+	}
+	findings, total, skipped, err := securityScan(sources)
+	if err != nil {
+		return err
+	}
+	for _, name := range skipped {
+		log.Print(tr("security.skipped", name))
+	}
+
+	// Die Sperrliste ist für fail2ban bzw. die Firewall bestimmt und enthält
+	// daher die echten Adressen
+	if *ipsOnly {
+		for _, ip := range banList(findings) {
+			fmt.Println(ip)
+		}
+		return nil
+	}
+
+	fmt.Println(tr("security.summary", total, len(findings)))
+	for _, f := range findings {
+		ip := f.IP
+		if ip == "" {
+			ip = "-"
+		}
+		fmt.Printf("%-5s %-22s %-16s %5d× %s\n", f.Severity, tr("security.kind."+f.Kind),
+			cfg.redactField("remoteAddr", ip), len(f.Entries), cfg.redactText(f.Detail))
+	}
+	return nil
+}
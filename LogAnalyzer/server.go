@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//go:embed web/index.html
+var indexTemplate string
+
+// Web-Oberfläche in der gewählten Sprache
+var indexPage = template.Must(template.New("index").Funcs(template.FuncMap{
+	"t":    tr,
+	"lang": func() string { return language },
+}).Parse(indexTemplate))
+
+// Maximale Anzahl Einträge, die der Server je Quelle im Speicher hält
+const maxServerEntries = 50000
+
+// serverSource hält die geparsten Einträge einer Quelle für die API
+type serverSource struct {
+	ID  int
+	cfg LogConfig
+
+	mu          sync.RWMutex
+	typ         string
+	entries     []LogEntry
+	parsed      int
+	failed      int
+	err         error
+	subscribers map[chan LogEntry]struct{}
+}
+
+// run verfolgt die Quelle, bis ctx beendet wird
+func (s *serverSource) run(ctx context.Context) {
+	err := tailEntries(ctx, s.cfg, false, func(r *entryReader, results []parsedLine) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.typ, s.parsed, s.failed, s.err = r.typ, r.parsed, r.failed, r.err
+		for _, p := range results {
+			if p.err != nil || !shouldLog(s.cfg.LogLevel, p.entry.Severity) {
+				continue
+			}
+			s.entries = append(s.entries, p.entry)
+			for ch := range s.subscribers {
+				// Langsame Clients verpassen Einträge, statt die Quelle zu blockieren
+				select {
+				case ch <- p.entry:
+				default:
+				}
+			}
+		}
+		if len(s.entries) > maxServerEntries {
+			s.entries = append([]LogEntry(nil), s.entries[len(s.entries)-maxServerEntries:]...)
+		}
+	})
+	if err != nil {
+		log.Print(tr("source.error", s.cfg.Name(), err))
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+	}
+}
+
+func (s *serverSource) subscribe() chan LogEntry {
+	ch := make(chan LogEntry, 100)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *serverSource) unsubscribe(ch chan LogEntry) {
+	s.mu.Lock()
+	delete(s.subscribers, ch)
+	s.mu.Unlock()
+}
+
+// entryFilter filtert Einträge nach Level, Zeitraum und Text
+type entryFilter struct {
+	level string
+	from  time.Time
+	to    time.Time
+	text  string
+	where map[string]string // Metadata-Felder mit exakt diesem Wert
+}
+
+func parseEntryFilter(r *http.Request, loc *time.Location) (entryFilter, error) {
+	q := r.URL.Query()
+	f := entryFilter{
+		level: q.Get("level"),
+		text:  strings.ToLower(q.Get("q")),
+	}
+	if f.level != "" {
+		if _, ok := levelOrder[f.level]; !ok {
+			return f, errorf("server.unknownLevel", f.level)
+		}
+	}
+
+	var err error
+	if f.from, err = parseQueryTime(q.Get("from"), loc); err != nil {
+		return f, err
+	}
+	if f.to, err = parseQueryTime(q.Get("to"), loc); err != nil {
+		return f, err
+	}
+	if f.where, err = parseWhere(q["where"]); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// parseWhere liest Feldfilter der Form key=value, z.B. status=500
+func parseWhere(values []string) (map[string]string, error) {
+	where := map[string]string{}
+	for _, w := range values {
+		k, v, ok := strings.Cut(w, "=")
+		if !ok || k == "" {
+			return nil, errorf("index.where", w)
+		}
+		where[k] = v
+	}
+	return where, nil
+}
+
+// parseQueryTime akzeptiert RFC3339 oder die Werte eines datetime-local Feldes
+func parseQueryTime(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errorf("server.invalidTime", value)
+}
+
+func (f entryFilter) match(e LogEntry) bool {
+	if f.level != "" && !shouldLog(f.level, e.Severity) {
+		return false
+	}
+	if !f.from.IsZero() && e.Timestamp.Before(f.from) {
+		return false
+	}
+	if !f.to.IsZero() && e.Timestamp.After(f.to) {
+		return false
+	}
+	for k, v := range f.where {
+		if e.Metadata[k] != v {
+			return false
+		}
+	}
+	if f.text == "" {
+		return true
+	}
+	if strings.Contains(strings.ToLower(e.Message), f.text) {
+		return true
+	}
+	for _, v := range e.Metadata {
+		if strings.Contains(strings.ToLower(v), f.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// logServer stellt die Quellen über HTTP bereit
+type logServer struct {
+	config  *Config
+	sources []*serverSource
+}
+
+func newLogServer(cfg *Config) *logServer {
+	srv := &logServer{config: cfg}
+	for i, logCfg := range cfg.Logs {
+		srv.sources = append(srv.sources, &serverSource{
+			ID:          i,
+			cfg:         logCfg,
+			subscribers: map[chan LogEntry]struct{}{},
+		})
+	}
+	return srv
+}
+
+func (srv *logServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		indexPage.Execute(w, nil)
+	})
+	mux.HandleFunc("GET /api/sources", srv.handleSources)
+	mux.HandleFunc("GET /api/sources/{id}/entries", srv.handleEntries)
+	mux.HandleFunc("GET /api/sources/{id}/stream", srv.handleStream)
+	return mux
+}
+
+type apiSource struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	LogLevel string `json:"loglevel"`
+	Color    string `json:"color"`
+	Entries  int    `json:"entries"`
+	Parsed   int    `json:"parsed"`
+	Failed   int    `json:"failed"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (srv *logServer) handleSources(w http.ResponseWriter, r *http.Request) {
+	result := make([]apiSource, 0, len(srv.sources))
+	for _, s := range srv.sources {
+		s.mu.RLock()
+		src := apiSource{
+			ID:       s.ID,
+			Name:     s.cfg.Name(),
+			Type:     s.typ,
+			LogLevel: s.cfg.LogLevel,
+			Color:    s.cfg.Color,
+			Entries:  len(s.entries),
+			Parsed:   s.parsed,
+			Failed:   s.failed,
+		}
+		if s.err != nil {
+			src.Error = s.err.Error()
+		}
+		s.mu.RUnlock()
+		result = append(result, src)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+type apiEntries struct {
+	Total   int        `json:"total"`
+	Offset  int        `json:"offset"`
+	Limit   int        `json:"limit"`
+	Entries []LogEntry `json:"entries"`
+}
+
+func (srv *logServer) handleEntries(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.source(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseEntryFilter(r, srv.config.displayLoc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	q := r.URL.Query()
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > maxLogEntries {
+		limit = 100
+	}
+	// Standardmäßig neueste Einträge zuerst
+	desc := q.Get("order") != "asc"
+
+	// Gesucht wird in den geschwärzten Einträgen, sonst ließe sich ein
+	// geschwärzter Wert durch eine Suche danach bestätigen
+	s.mu.RLock()
+	var matches []LogEntry
+	for _, e := range s.entries {
+		if e = srv.config.redact(e); filter.match(e) {
+			matches = append(matches, e)
+		}
+	}
+	s.mu.RUnlock()
+
+	result := apiEntries{Total: len(matches), Offset: offset, Limit: limit, Entries: []LogEntry{}}
+	for i := offset; i < len(matches) && i < offset+limit; i++ {
+		e := matches[i]
+		if desc {
+			e = matches[len(matches)-1-i]
+		}
+		result.Entries = append(result.Entries, srv.apiEntry(e))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Intervall für Keep-Alive Kommentare im Event-Stream
+const streamKeepAlive = 15 * time.Second
+
+func (srv *logServer) handleStream(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.source(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseEntryFilter(r, srv.config.displayLoc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errorf("server.noStreaming"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			if e = srv.config.redact(e); !filter.match(e) {
+				continue
+			}
+			data, err := json.Marshal(srv.apiEntry(e))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// source liefert die Quelle aus dem Pfad oder schreibt einen Fehler
+func (srv *logServer) source(w http.ResponseWriter, r *http.Request) (*serverSource, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 0 || id >= len(srv.sources) {
+		writeError(w, http.StatusNotFound, errorf("server.sourceMissing", r.PathValue("id")))
+		return nil, false
+	}
+	return srv.sources[id], true
+}
+
+// apiEntry rechnet den Zeitstempel eines bereits geschwärzten Eintrags in
+// die Anzeige-Zeitzone um
+func (srv *logServer) apiEntry(e LogEntry) LogEntry {
+	e.Timestamp = srv.config.displayTime(e.Timestamp)
+	return e
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// runServe startet den Web-Modus: "analyzer serve [-addr 127.0.0.1:8080]"
+func runServe(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "listen address")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := newLogServer(cfg)
+	for _, s := range srv.sources {
+		go s.run(ctx)
+	}
+
+	httpServer := &http.Server{
+		Addr:    *addr,
+		Handler: srv.handler(),
+		// Offene Event-Streams beim Beenden schließen
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Print(tr("server.listening", *addr))
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
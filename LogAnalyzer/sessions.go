@@ -0,0 +1,453 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Pause, nach der eine neue Sitzung beginnt
+const defaultSessionGap = 30 * time.Minute
+
+// Anzahl vorgeschlagener Benutzer bzw. IPs in der Auswahl, andere lassen sich
+// mit / eingeben, und Einträge je aufgeklappter Sitzung
+const (
+	maxActors         = 20
+	maxSessionEntries = 200
+)
+
+// actor ist ein Benutzer oder eine IP, deren Aktivität gezeigt wird
+type actor struct {
+	Field string // user oder remoteAddr
+	Value string
+	Count int
+}
+
+// actorValue liefert den Benutzer bzw. die IP eines Eintrags. Nextcloud
+// schreibt "--" für nicht angemeldete Benutzer.
+func actorValue(e LogEntry, field string) string {
+	if field == "remoteAddr" {
+		return entryIP(e)
+	}
+	if v := e.Metadata[field]; v != "--" {
+		return v
+	}
+	return ""
+}
+
+// activityEntry ist ein Eintrag mit dem Namen seiner Quelle
+type activityEntry struct {
+	source string
+	entry  LogEntry
+}
+
+// session ist eine Folge von Einträgen ohne längere Pause
+type session struct {
+	entries []activityEntry
+}
+
+func (s session) start() time.Time { return s.entries[0].entry.Timestamp }
+func (s session) end() time.Time   { return s.entries[len(s.entries)-1].entry.Timestamp }
+
+// readActivity liest alle Dateien vollständig. Kommandos wie "docker logs -f"
+// und stdin enden nicht und werden übersprungen. Quellen, die sich nicht lesen
+// lassen, werden ausgelassen und ihre Fehler geliefert.
+func readActivity(sources []LogConfig) ([]activityEntry, []error) {
+	var all []activityEntry
+	var failed []error
+	for _, src := range sources {
+		if src.Command != "" || src.isStdin() {
+			continue
+		}
+		src.LogLevel = "debug"
+		entries, _, err := readEntries(src)
+		if err != nil {
+			failed = append(failed, errorf("source.error", src.Name(), err))
+			continue
+		}
+		for _, e := range entries {
+			all = append(all, activityEntry{source: src.Name(), entry: e})
+		}
+	}
+	return all, failed
+}
+
+// topActors liefert die aktivsten Benutzer und IPs
+func topActors(all []activityEntry) []actor {
+	var actors []actor
+	for _, field := range []string{"user", "remoteAddr"} {
+		counts := map[string]int{}
+		for _, a := range all {
+			if v := actorValue(a.entry, field); v != "" {
+				counts[v]++
+			}
+		}
+		var list []actor
+		for v, n := range counts {
+			list = append(list, actor{Field: field, Value: v, Count: n})
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Value < list[j].Value
+		})
+		if len(list) > maxActors {
+			list = list[:maxActors]
+		}
+		actors = append(actors, list...)
+	}
+	return actors
+}
+
+// buildSessions sammelt die Einträge eines Benutzers bzw. einer IP, sortiert
+// sie nach Zeit und teilt sie bei Pausen über gap in Sitzungen
+func buildSessions(all []activityEntry, a actor, gap time.Duration) []session {
+	var matching []activityEntry
+	for _, e := range all {
+		if actorValue(e.entry, a.Field) == a.Value {
+			matching = append(matching, e)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].entry.Timestamp.Before(matching[j].entry.Timestamp)
+	})
+
+	var sessions []session
+	for i, e := range matching {
+		if i == 0 || e.entry.Timestamp.Sub(matching[i-1].entry.Timestamp) > gap {
+			sessions = append(sessions, session{})
+		}
+		last := &sessions[len(sessions)-1]
+		last.entries = append(last.entries, e)
+	}
+	return sessions
+}
+
+// summary fasst eine Sitzung zusammen: Level, Quellen und je nach Art die
+// IPs bzw. Benutzer, Apps und URLs
+func (s session) summary(cfg *Config, field string) []string {
+	counts := map[string]int{}
+	var sources, others, apps, urls []string
+	other := "user"
+	if field == "user" {
+		other = "remoteAddr"
+	}
+	for _, a := range s.entries {
+		counts[a.entry.Severity]++
+		sources = append(sources, a.source)
+		others = append(others, cfg.redactField(other, actorValue(a.entry, other)))
+		apps = append(apps, a.entry.Metadata["app"])
+		urls = append(urls, cfg.redactField("url", urlPattern(a.entry.Metadata["url"])))
+	}
+
+	var levels []string
+	for _, level := range severityLevels {
+		if counts[level] > 0 {
+			levels = append(levels, fmt.Sprintf("%s %d", level, counts[level]))
+		}
+	}
+	parts := []string{strings.Join(levels, ", "), tr("sessions.sources", joinDistinct(sources))}
+	if v := joinDistinct(others); v != "" {
+		parts = append(parts, tr("sessions."+other, v))
+	}
+	if v := joinDistinct(apps); v != "" {
+		parts = append(parts, tr("sessions.apps", v))
+	}
+	if v := joinDistinct(urls); v != "" {
+		parts = append(parts, tr("sessions.urls", v))
+	}
+	return parts
+}
+
+// renderSessions erstellt die Zeilen der Zeitleiste. Aufgeklappte Sitzungen
+// zeigen ihre Einträge, die gewählte wird hervorgehoben.
+func renderSessions(cfg *Config, a actor, sessions []session, selected int, expanded map[int]bool) ([]string, int) {
+	total := 0
+	for _, s := range sessions {
+		total += len(s.entries)
+	}
+	lines := []string{
+		titleStyle.Render(tr("sessions.title."+a.Field, cfg.redactField(a.Field, a.Value))),
+		helpStyle.Render(tr("sessions.summary", total, len(sessions))),
+		"",
+	}
+	if len(sessions) == 0 {
+		lines = append(lines, helpStyle.Render(tr("sessions.none")))
+	}
+
+	selectedLine := 0
+	for i, s := range sessions {
+		duration := s.end().Sub(s.start()).Round(time.Second)
+		head := tr("sessions.session", i+1,
+			cfg.displayTime(s.start()).Format("02.01.2006 15:04:05"),
+			cfg.displayTime(s.end()).Format("15:04:05"),
+			duration, len(s.entries))
+		if i == selected {
+			selectedLine = len(lines)
+			lines = append(lines, selectedStyle.Render("▸ "+head))
+		} else {
+			lines = append(lines, logLineStyle.Render(" "+head))
+		}
+		lines = append(lines, helpStyle.Render("    "+strings.Join(s.summary(cfg, a.Field), " | ")))
+
+		if !expanded[i] {
+			continue
+		}
+		for j, ae := range s.entries {
+			if j == maxSessionEntries {
+				lines = append(lines, helpStyle.Render(fmt.Sprintf("      … %d", len(s.entries)-j)))
+				break
+			}
+			e := cfg.redact(ae.entry)
+			severityStyle := lipgloss.NewStyle().Foreground(severityColor(e.Severity))
+			lines = append(lines, fmt.Sprintf("      %s %s %s %s",
+				cfg.displayTime(e.Timestamp).Format("15:04:05"),
+				severityStyle.Render(fmt.Sprintf("%-5s", e.Severity)),
+				helpStyle.Render(fitCell(ae.source, 20)),
+				e.Message))
+		}
+	}
+	return lines, selectedLine
+}
+
+// sessionState hält den Aktivitäts-Bildschirm: erst die Auswahl eines
+// Benutzers bzw. einer IP, danach deren Sitzungen
+type sessionState struct {
+	entries  []activityEntry
+	actors   []actor
+	actor    *actor // gewählt, sonst wird die Auswahl gezeigt
+	sessions []session
+	selected int
+	expanded map[int]bool
+	loaded   bool
+	failed   []error // ausgelassene Quellen
+	entering bool    // Benutzer oder IP wird eingegeben
+	input    textinput.Model
+}
+
+// sessionMsg liefert die eingelesenen Einträge an die TUI
+type sessionMsg struct {
+	entries []activityEntry
+	actor   *actor
+	failed  []error
+}
+
+// startSessions liest alle Quellen im Hintergrund. Mit a wird direkt dessen
+// Zeitleiste gezeigt, z.B. für die IP eines Sicherheitsfunds.
+func (m *model) startSessions(a *actor) tea.Cmd {
+	m.screen = screenSessions
+	m.sessions = sessionState{expanded: map[int]bool{}}
+	m.showError(tr("sessions.running"))
+	sources := m.config.Logs
+	return func() tea.Msg {
+		entries, failed := readActivity(sources)
+		return sessionMsg{entries: entries, actor: a, failed: failed}
+	}
+}
+
+// showSessions übernimmt die eingelesenen Einträge
+func (m *model) showSessions(msg sessionMsg) {
+	if m.screen != screenSessions {
+		return
+	}
+	s := &m.sessions
+	s.entries = msg.entries
+	s.failed = msg.failed
+	s.actors = topActors(msg.entries)
+	s.loaded = true
+	if msg.actor != nil {
+		m.selectActor(*msg.actor)
+		return
+	}
+	m.viewport.GotoTop()
+	m.renderSessions()
+}
+
+// selectActor zeigt die Sitzungen eines Benutzers bzw. einer IP
+func (m *model) selectActor(a actor) {
+	s := &m.sessions
+	s.actor = &a
+	s.sessions = buildSessions(s.entries, a, defaultSessionGap)
+	s.expanded = map[int]bool{}
+	// Die letzte Sitzung ist meist die gesuchte
+	s.selected = max(len(s.sessions)-1, 0)
+	m.renderSessions()
+}
+
+// startInput fragt nach einem Benutzer oder einer IP, die nicht unter den
+// Vorschlägen ist
+func (m *model) startInput() tea.Cmd {
+	input := textinput.New()
+	input.Prompt = tr("sessions.prompt")
+	input.CharLimit = 256
+	m.sessions.input = input
+	m.sessions.entering = true
+	return m.sessions.input.Focus()
+}
+
+// updateInput verarbeitet die Eingabe; IP-Adressen werden erkannt, alles
+// andere gilt als Benutzer
+func (m model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := &m.sessions
+	switch msg.Type {
+	case tea.KeyEsc:
+		s.entering = false
+		return m, nil
+	case tea.KeyEnter:
+		value := strings.TrimSpace(s.input.Value())
+		if value == "" {
+			return m, nil
+		}
+		s.entering = false
+		a := actor{Field: "user", Value: value}
+		if net.ParseIP(value) != nil {
+			a.Field = "remoteAddr"
+		}
+		m.selectActor(a)
+		return m, nil
+	}
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateSessions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := &m.sessions
+	if s.entering {
+		return m.updateInput(msg)
+	}
+	count := len(s.actors)
+	if s.actor != nil {
+		count = len(s.sessions)
+	}
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		if s.actor != nil && len(s.actors) > 0 {
+			s.actor = nil
+			s.selected = 0
+			m.renderSessions()
+			return m, nil
+		}
+		m.screen = screenList
+		return m, nil
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Reload):
+		return m, m.startSessions(s.actor)
+	case !s.loaded:
+		return m, nil
+	case key.Matches(msg, m.keys.Search):
+		return m, m.startInput()
+	case key.Matches(msg, m.keys.Up):
+		if s.selected > 0 {
+			s.selected--
+		}
+		m.renderSessions()
+		return m, nil
+	case key.Matches(msg, m.keys.Down):
+		if s.selected < count-1 {
+			s.selected++
+		}
+		m.renderSessions()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		if s.actor == nil {
+			if count > 0 {
+				m.selectActor(s.actors[s.selected])
+			}
+		} else {
+			s.expanded[s.selected] = !s.expanded[s.selected]
+			m.renderSessions()
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// renderSessions zeigt die Auswahl bzw. die Zeitleiste
+func (m *model) renderSessions() {
+	s := m.sessions
+	var lines []string
+	selectedLine := 0
+	if s.actor != nil {
+		lines, selectedLine = renderSessions(m.config, *s.actor, s.sessions, s.selected, s.expanded)
+	} else {
+		lines = []string{titleStyle.Render(tr("sessions.choose")), ""}
+		if len(s.actors) == 0 {
+			lines = append(lines, helpStyle.Render(tr("sessions.noActors")))
+		}
+		for i, a := range s.actors {
+			line := fmt.Sprintf("%-10s %-40s %6d", tr("sessions.field."+a.Field), m.config.redactField(a.Field, a.Value), a.Count)
+			if i == s.selected {
+				selectedLine = len(lines)
+				lines = append(lines, selectedStyle.Render("▸ "+line))
+			} else {
+				lines = append(lines, logLineStyle.Render(" "+line))
+			}
+		}
+	}
+
+	// Ausgelassene Quellen über den Ergebnissen melden
+	var notices []string
+	for _, err := range s.failed {
+		notices = append(notices, parseErrorStyle.Render(tr("sessions.skipped", err)))
+	}
+	if len(notices) > 0 {
+		lines = append(append(notices, ""), lines...)
+		selectedLine += len(notices) + 1
+	}
+
+	m.logs = lines
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	if selectedLine < m.viewport.YOffset {
+		m.viewport.SetYOffset(selectedLine)
+	} else if selectedLine >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(selectedLine - m.viewport.Height + 1)
+	}
+}
+
+// runSessions zeigt die Sitzungen auf der Kommandozeile:
+// "analyzer sessions [-gap 30m] [-entries] -user <name> | -ip <adresse>"
+func runSessions(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("sessions", flag.ExitOnError)
+	user := fs.String("user", "", "Nextcloud user")
+	ip := fs.String("ip", "", "remote address")
+	gap := fs.Duration("gap", defaultSessionGap, "inactivity that starts a new session")
+	withEntries := fs.Bool("entries", false, "list the entries of every session")
+	fs.Parse(args)
+
+	a := actor{Field: "user", Value: *user}
+	if *ip != "" {
+		a = actor{Field: "remoteAddr", Value: *ip}
+	}
+	if (*user == "") == (*ip == "") {
+		return errorf("sessions.usage")
+	}
+
+	all, failed := readActivity(cfg.Logs)
+	for _, err := range failed {
+		log.Print(tr("sessions.skipped", err))
+	}
+	sessions := buildSessions(all, a, *gap)
+	expanded := map[int]bool{}
+	for i := range sessions {
+		expanded[i] = *withEntries
+	}
+	lines, _ := renderSessions(cfg, a, sessions, -1, expanded)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return nil
+}
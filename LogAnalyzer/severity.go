@@ -0,0 +1,107 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SeverityRule setzt das Level von Einträgen, deren Felder alle auf die
+// Ausdrücke passen. Neben den Metadata-Feldern des Parsers gibt es message,
+// source und severity; die Regeln gelten vor der Anreicherung. Die erste
+// passende Regel einer Quelle gilt.
+type SeverityRule struct {
+	Match    map[string]string `yaml:"match"`    // Feld und regulärer Ausdruck
+	Severity string            `yaml:"severity"` // neues Level
+}
+
+// severityRule ist eine übersetzte Regel
+type severityRule struct {
+	fields   []string // sortiert, damit die Reihenfolge stabil bleibt
+	patterns []*regexp.Regexp
+	severity string
+}
+
+// compileSeverityRules prüft und übersetzt die Regeln einer Quelle
+func compileSeverityRules(rules []SeverityRule) ([]severityRule, error) {
+	compiled := make([]severityRule, 0, len(rules))
+	for i, rule := range rules {
+		if _, ok := levelOrder[rule.Severity]; !ok {
+			return nil, errorf("severity.level", i+1, rule.Severity)
+		}
+		if len(rule.Match) == 0 {
+			return nil, errorf("severity.empty", i+1)
+		}
+		r := severityRule{severity: rule.Severity}
+		for field := range rule.Match {
+			r.fields = append(r.fields, field)
+		}
+		sort.Strings(r.fields)
+		for _, field := range r.fields {
+			re, err := regexp.Compile(rule.Match[field])
+			if err != nil {
+				return nil, errorf("severity.pattern", i+1, field, err)
+			}
+			r.patterns = append(r.patterns, re)
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// entryField liefert ein Feld eines Eintrags für die Regeln
+func entryField(e LogEntry, field string) string {
+	switch field {
+	case "message":
+		return e.Message
+	case "source":
+		return e.Source
+	case "severity":
+		return e.Severity
+	}
+	return e.Metadata[field]
+}
+
+func (r severityRule) matches(e LogEntry) bool {
+	for i, field := range r.fields {
+		if !r.patterns[i].MatchString(entryField(e, field)) {
+			return false
+		}
+	}
+	return true
+}
+
+// applySeverityRules setzt das Level nach der ersten passenden Regel
+func applySeverityRules(rules []severityRule, e LogEntry) LogEntry {
+	for _, r := range rules {
+		if r.matches(e) {
+			e.Severity = r.severity
+			break
+		}
+	}
+	return e
+}
+
+// severityFingerprint beschreibt die Regeln für den Index, z.B.
+// `"app"="^admin_audit$">warn`. Felder und Ausdrücke sind gequotet, damit
+// sich verschiedene Regeln nicht gleich beschreiben lassen.
+func severityFingerprint(rules []SeverityRule) string {
+	parts := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		fields := make([]string, 0, len(rule.Match))
+		for field := range rule.Match {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		var b strings.Builder
+		for i, field := range fields {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Quote(field) + "=" + strconv.Quote(rule.Match[field]))
+		}
+		parts = append(parts, b.String()+">"+rule.Severity)
+	}
+	return strings.Join(parts, ";")
+}
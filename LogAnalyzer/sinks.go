@@ -0,0 +1,620 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// SinkConfig beschreibt ein Ziel, an das geparste Einträge weitergeleitet werden
+type SinkConfig struct {
+	Type          string            `yaml:"type"`          // loki, elasticsearch oder gelf
+	URL           string            `yaml:"url"`           // Loki Push-API bzw. Elasticsearch-Basis-URL
+	Address       string            `yaml:"address"`       // GELF: host:port
+	Protocol      string            `yaml:"protocol"`      // GELF: udp (Standard) oder tcp
+	Index         string            `yaml:"index"`         // Elasticsearch-Index
+	Labels        map[string]string `yaml:"labels"`        // zusätzliche feste Labels/Felder
+	BatchSize     int               `yaml:"batchsize"`     // Einträge je Übertragung
+	FlushInterval string            `yaml:"flushinterval"` // z.B. "5s"
+	MaxRetries    int               `yaml:"maxretries"`
+}
+
+// forwardedEntry ist ein Eintrag mit dem Namen seiner Quelle. LogEntry.Source
+// nennt nur den Parser und unterscheidet z.B. zwei Apache-Logs nicht.
+type forwardedEntry struct {
+	source string // LogConfig.Name()
+	entry  LogEntry
+}
+
+// Sink überträgt einen Stapel Einträge an ein externes System
+type Sink interface {
+	Send(ctx context.Context, entries []forwardedEntry) error
+	Close() error
+}
+
+// permanentError kennzeichnet Fehler, bei denen eine Wiederholung nicht hilft
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+// partialError meldet, dass nur ein Teil des Stapels angenommen wurde. Nur
+// die Einträge in retry werden wiederholt, die übrigen Fehler sind endgültig.
+type partialError struct {
+	retry   []forwardedEntry
+	dropped int
+	err     error
+}
+
+func (e partialError) Error() string { return e.err.Error() }
+
+// Wartezeit für eine Übertragung, auch wenn der Empfänger nicht mehr liest
+const sinkTimeout = 30 * time.Second
+
+// newSink erstellt den Sink für die Konfiguration
+func newSink(cfg SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case "loki":
+		if cfg.URL == "" {
+			return nil, errorf("sink.missingURL", "loki")
+		}
+		return &lokiSink{url: cfg.URL, labels: cfg.Labels, client: &http.Client{Timeout: sinkTimeout}}, nil
+	case "elasticsearch":
+		if cfg.URL == "" {
+			return nil, errorf("sink.missingURL", "elasticsearch")
+		}
+		index := cfg.Index
+		if index == "" {
+			index = "logs"
+		}
+		return &elasticSink{url: cfg.URL, index: index, fields: cfg.Labels, client: &http.Client{Timeout: sinkTimeout}}, nil
+	case "gelf":
+		if cfg.Address == "" {
+			return nil, errorf("sink.missingAddress", "gelf")
+		}
+		protocol := cfg.Protocol
+		if protocol == "" {
+			protocol = "udp"
+		}
+		if protocol != "udp" && protocol != "tcp" {
+			return nil, errorf("sink.unknownProtocol", "gelf", protocol)
+		}
+		host, _ := os.Hostname()
+		return &gelfSink{address: cfg.Address, protocol: protocol, host: host, fields: cfg.Labels}, nil
+	}
+	return nil, errorf("sink.unknownType", cfg.Type)
+}
+
+// postHTTP sendet body und wertet den Statuscode aus. 4xx-Fehler (außer 429)
+// werden nicht wiederholt.
+func postHTTP(ctx context.Context, client *http.Client, url, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode >= 300 {
+		err := fmt.Errorf("%s: %s %s", url, resp.Status, bytes.TrimSpace(respBody))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, permanentError{err}
+		}
+		return nil, err
+	}
+	return respBody, nil
+}
+
+// lokiSink nutzt die Loki Push-API (/loki/api/v1/push)
+type lokiSink struct {
+	url    string
+	labels map[string]string
+	client *http.Client
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *lokiSink) Send(ctx context.Context, entries []forwardedEntry) error {
+	// Ein Stream je Kombination aus Quelle, Parser und Level
+	streams := map[[3]string]*lokiStream{}
+	var order [][3]string
+	for _, f := range entries {
+		e := f.entry
+		key := [3]string{f.source, e.Source, e.Severity}
+		stream, ok := streams[key]
+		if !ok {
+			labels := map[string]string{"source": f.source, "parser": e.Source, "severity": e.Severity}
+			for k, v := range s.labels {
+				labels[k] = v
+			}
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			order = append(order, key)
+		}
+
+		// Die Zeile als JSON, damit Metadaten mit "| json" auswertbar bleiben
+		line := map[string]string{"message": e.Message}
+		for k, v := range e.Metadata {
+			if v != "" && k != "message" {
+				line[k] = v
+			}
+		}
+		data, _ := json.Marshal(line)
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(e.Timestamp.UnixNano(), 10), string(data)})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return permanentError{err}
+	}
+	_, err = postHTTP(ctx, s.client, s.url, "application/json", body)
+	return err
+}
+
+func (s *lokiSink) Close() error { return nil }
+
+// elasticSink nutzt die Bulk-API von Elasticsearch
+type elasticSink struct {
+	url    string
+	index  string
+	fields map[string]string
+	client *http.Client
+}
+
+func (s *elasticSink) Send(ctx context.Context, entries []forwardedEntry) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, f := range entries {
+		e := f.entry
+		doc := map[string]interface{}{
+			"@timestamp": e.Timestamp.Format(time.RFC3339Nano),
+			"source":     f.source,
+			"parser":     e.Source,
+			"severity":   e.Severity,
+			"message":    e.Message,
+		}
+		if len(e.Metadata) > 0 {
+			doc["metadata"] = e.Metadata
+		}
+		for k, v := range s.fields {
+			doc[k] = v
+		}
+		enc.Encode(map[string]interface{}{"index": map[string]string{"_index": s.index}})
+		enc.Encode(doc)
+	}
+
+	respBody, err := postHTTP(ctx, s.client, s.url+"/_bulk", "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// Fehler einzelner Dokumente stehen nur in der Antwort, in der
+	// Reihenfolge der Anfrage
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []struct {
+			Index struct {
+				Status int             `json:"status"`
+				Error  json.RawMessage `json:"error"`
+			} `json:"index"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil || !result.Errors {
+		return nil
+	}
+	if len(result.Items) != len(entries) {
+		return permanentError{errorf("sink.rejected", len(entries), string(respBody))}
+	}
+
+	partial := partialError{}
+	var firstError json.RawMessage
+	for i, item := range result.Items {
+		status := item.Index.Status
+		switch {
+		case status >= 200 && status < 300:
+			continue
+		case status == http.StatusTooManyRequests || status >= 500:
+			partial.retry = append(partial.retry, entries[i])
+		default:
+			partial.dropped++
+		}
+		if firstError == nil {
+			firstError = item.Index.Error
+		}
+	}
+	partial.err = errorf("sink.rejected", len(partial.retry)+partial.dropped, string(firstError))
+	return partial
+}
+
+func (s *elasticSink) Close() error { return nil }
+
+// Syslog-Level für GELF
+var gelfLevels = map[string]int{
+	"debug": 7,
+	"info":  6,
+	"warn":  4,
+	"error": 3,
+	"fatal": 2,
+}
+
+// Maximale Größe eines GELF-UDP-Pakets, größere Nachrichten werden aufgeteilt
+const (
+	gelfChunkSize = 8192
+	gelfMaxChunks = 128
+)
+
+// gelfSink sendet GELF-Nachrichten per UDP oder TCP (z.B. an Graylog)
+type gelfSink struct {
+	address  string
+	protocol string
+	host     string
+	fields   map[string]string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (s *gelfSink) message(source string, e LogEntry) ([]byte, error) {
+	// GELF verlangt eine nicht leere short_message
+	short := e.Message
+	if short == "" {
+		short = "-"
+	}
+	msg := map[string]interface{}{
+		"version":       "1.1",
+		"host":          s.host,
+		"short_message": short,
+		"timestamp":     float64(e.Timestamp.UnixNano()) / float64(time.Second),
+		"level":         gelfLevels[e.Severity],
+		"_source":       source,
+		"_parser":       e.Source,
+		"_severity":     e.Severity,
+	}
+	for k, v := range e.Metadata {
+		if v != "" && k != "id" {
+			msg["_"+k] = v
+		}
+	}
+	for k, v := range s.fields {
+		msg["_"+k] = v
+	}
+	return json.Marshal(msg)
+}
+
+func (s *gelfSink) Send(ctx context.Context, entries []forwardedEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, s.protocol, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	// Ohne Frist würde ein Empfänger, der nicht mehr liest, den Worker und
+	// damit das Beenden für immer aufhalten
+	deadline := time.Now().Add(sinkTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	for _, f := range entries {
+		data, err := s.message(f.source, f.entry)
+		if err != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.conn.SetWriteDeadline(deadline)
+		if s.protocol == "tcp" {
+			// TCP-Nachrichten werden mit einem Null-Byte getrennt
+			_, err = s.conn.Write(append(data, 0))
+		} else {
+			err = s.writeUDP(data)
+		}
+		if err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// writeUDP sendet eine Nachricht, bei Bedarf in GELF-Chunks aufgeteilt
+func (s *gelfSink) writeUDP(data []byte) error {
+	if len(data) <= gelfChunkSize {
+		_, err := s.conn.Write(data)
+		return err
+	}
+
+	payload := gelfChunkSize - 12
+	count := (len(data) + payload - 1) / payload
+	if count > gelfMaxChunks {
+		return errorf("sink.tooLarge", len(data))
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payload
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := append([]byte{0x1e, 0x0f}, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data[i*payload:end]...)
+		if _, err := s.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *gelfSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// sinkWorker sammelt Einträge und überträgt sie gebündelt mit Wiederholungen
+type sinkWorker struct {
+	name       string
+	sink       Sink
+	batchSize  int
+	interval   time.Duration
+	maxRetries int
+	backoff    time.Duration // Wartezeit vor der ersten Wiederholung
+	entries    chan forwardedEntry
+
+	wait    bool         // bei voller Warteschlange warten statt verwerfen
+	failing atomic.Bool  // Übertragung schlägt gerade fehl
+	dropped atomic.Int64 // verworfene Einträge seit der letzten Meldung
+}
+
+// Standardwerte für die Übertragung
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = 5 * time.Second
+	defaultMaxRetries    = 5
+	firstRetryBackoff    = 500 * time.Millisecond
+	maxRetryBackoff      = time.Minute
+	queueBatches         = 20 // Größe der Warteschlange in Stapeln
+)
+
+func newSinkWorker(cfg SinkConfig) (*sinkWorker, error) {
+	sink, err := newSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &sinkWorker{
+		name:       cfg.Type,
+		sink:       sink,
+		batchSize:  cfg.BatchSize,
+		interval:   defaultFlushInterval,
+		maxRetries: cfg.MaxRetries,
+		backoff:    firstRetryBackoff,
+	}
+	if w.batchSize <= 0 {
+		w.batchSize = defaultBatchSize
+	}
+	if w.maxRetries <= 0 {
+		w.maxRetries = defaultMaxRetries
+	}
+	if cfg.FlushInterval != "" {
+		if w.interval, err = time.ParseDuration(cfg.FlushInterval); err != nil {
+			return nil, errorf("sink.flushInterval", cfg.Type, cfg.FlushInterval)
+		}
+	}
+	w.entries = make(chan forwardedEntry, w.batchSize*queueBatches)
+	return w, nil
+}
+
+// enqueue übergibt einen Eintrag, ohne die Quellen aufzuhalten: ist die
+// Warteschlange voll, wird er verworfen und gezählt. Nur beim Nachsenden
+// vorhandener Zeilen (wait) wird auf einen erreichbaren Sink gewartet.
+func (w *sinkWorker) enqueue(e forwardedEntry) {
+	select {
+	case w.entries <- e:
+		return
+	default:
+	}
+	if w.wait && !w.failing.Load() {
+		w.entries <- e
+		return
+	}
+	w.dropped.Add(1)
+}
+
+// reportDropped meldet, wie viele Einträge die volle Warteschlange verworfen hat
+func (w *sinkWorker) reportDropped() {
+	if n := w.dropped.Swap(0); n > 0 {
+		log.Print(tr("sink.overflow", w.name, n))
+	}
+}
+
+// run überträgt gesammelte Einträge, sobald der Stapel voll oder das Intervall
+// abgelaufen ist. Nach dem Schließen von entries wird der Rest übertragen.
+func (w *sinkWorker) run(ctx context.Context) {
+	defer w.sink.Close()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var batch []forwardedEntry
+	for {
+		select {
+		case e, ok := <-w.entries:
+			if !ok {
+				w.send(ctx, batch)
+				w.reportDropped()
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) >= w.batchSize {
+				w.send(ctx, batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			w.send(ctx, batch)
+			batch = nil
+			w.reportDropped()
+		}
+	}
+}
+
+// send überträgt einen Stapel mit exponentiellem Backoff. Ist ctx beendet,
+// erfolgt noch ein letzter Versuch, der höchstens sinkTimeout dauert.
+func (w *sinkWorker) send(ctx context.Context, batch []forwardedEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	defer w.failing.Store(false)
+
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		sendCtx := ctx
+		if ctx.Err() != nil {
+			sendCtx = context.WithoutCancel(ctx)
+		}
+		sendCtx, cancel := context.WithTimeout(sendCtx, sinkTimeout)
+		err := w.sink.Send(sendCtx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		// Angenommene und endgültig abgelehnte Einträge nicht wiederholen
+		var partial partialError
+		if errors.As(err, &partial) {
+			if partial.dropped > 0 {
+				log.Print(tr("sink.dropped", w.name, partial.dropped, err))
+			}
+			batch = partial.retry
+			if len(batch) == 0 {
+				return
+			}
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= w.maxRetries || ctx.Err() != nil {
+			log.Print(tr("sink.dropped", w.name, len(batch), err))
+			return
+		}
+		w.failing.Store(true)
+		log.Print(tr("sink.retry", w.name, backoff, err))
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// runForward leitet alle Quellen an die Sinks weiter: "analyzer forward [-from-start]"
+func runForward(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("forward", flag.ExitOnError)
+	fromStart := fs.Bool("from-start", false, "forward existing lines of log files")
+	fs.Parse(args)
+
+	if len(cfg.Sinks) == 0 {
+		return errorf("sink.none")
+	}
+
+	var workers []*sinkWorker
+	for _, sc := range cfg.Sinks {
+		w, err := newSinkWorker(sc)
+		if err != nil {
+			return err
+		}
+		w.wait = *fromStart
+		workers = append(workers, w)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workerWG sync.WaitGroup
+	for _, w := range workers {
+		workerWG.Add(1)
+		go func(w *sinkWorker) {
+			defer workerWG.Done()
+			w.run(ctx)
+		}(w)
+	}
+
+	var sourceWG sync.WaitGroup
+	for _, logCfg := range cfg.Logs {
+		sourceWG.Add(1)
+		go func(logCfg LogConfig) {
+			defer sourceWG.Done()
+			err := tailEntries(ctx, logCfg, !*fromStart, func(r *entryReader, results []parsedLine) {
+				for _, p := range results {
+					if p.err != nil || !shouldLog(logCfg.LogLevel, p.entry.Severity) {
+						continue
+					}
+					entry := forwardedEntry{source: logCfg.Name(), entry: cfg.redact(p.entry)}
+					for _, w := range workers {
+						w.enqueue(entry)
+					}
+				}
+			})
+			if err != nil {
+				log.Print(tr("source.error", logCfg.Name(), err))
+			}
+		}(logCfg)
+	}
+
+	log.Print(tr("sink.forwarding", len(cfg.Logs), len(workers)))
+	sourceWG.Wait()
+
+	// Restliche Einträge übertragen
+	for _, w := range workers {
+		close(w.entries)
+	}
+	workerWG.Wait()
+	return nil
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubServer zeichnet die Anfragen auf und antwortet der Reihe nach mit
+// den Antworten aus replies; danach immer mit der letzten
+type stubServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests [][]byte
+}
+
+type stubReply struct {
+	status int
+	body   string
+}
+
+func newStubServer(t *testing.T, replies ...stubReply) *stubServer {
+	s := &stubServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		n := len(s.requests)
+		s.requests = append(s.requests, body)
+		s.mu.Unlock()
+
+		reply := replies[min(n, len(replies)-1)]
+		w.WriteHeader(reply.status)
+		io.WriteString(w, reply.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubServer) received() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.requests...)
+}
+
+func testEntries(source string, messages ...string) []forwardedEntry {
+	var entries []forwardedEntry
+	for i, msg := range messages {
+		entries = append(entries, forwardedEntry{source: source, entry: LogEntry{
+			Timestamp: time.Date(2024, 10, 10, 13, 55, i, 0, time.UTC),
+			Source:    "apache",
+			Severity:  "info",
+			Message:   msg,
+			Metadata:  map[string]string{"status": "200"},
+		}})
+	}
+	return entries
+}
+
+func testWorker(t *testing.T, cfg SinkConfig) *sinkWorker {
+	w, err := newSinkWorker(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.backoff = time.Millisecond
+	return w
+}
+
+func TestLokiStreamsBySource(t *testing.T) {
+	srv := newStubServer(t, stubReply{status: http.StatusNoContent})
+	sink, _ := newSink(SinkConfig{Type: "loki", URL: srv.URL, Labels: map[string]string{"job": "logs"}})
+
+	entries := append(testEntries("/var/log/a.log", "one", "two"), testEntries("/var/log/b.log", "three")...)
+	if err := sink.Send(context.Background(), entries); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	if err := json.Unmarshal(srv.received()[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Streams) != 2 {
+		t.Fatalf("got %d streams, want 2", len(payload.Streams))
+	}
+	for i, want := range []string{"/var/log/a.log", "/var/log/b.log"} {
+		labels := payload.Streams[i].Stream
+		if labels["source"] != want || labels["parser"] != "apache" || labels["job"] != "logs" {
+			t.Errorf("stream %d: labels %v", i, labels)
+		}
+	}
+	if n := len(payload.Streams[0].Values); n != 2 {
+		t.Errorf("got %d values in first stream, want 2", n)
+	}
+}
+
+func TestWorkerRetriesServerErrors(t *testing.T) {
+	srv := newStubServer(t,
+		stubReply{status: http.StatusTooManyRequests},
+		stubReply{status: http.StatusBadGateway},
+		stubReply{status: http.StatusNoContent},
+	)
+	w := testWorker(t, SinkConfig{Type: "loki", URL: srv.URL})
+	w.send(context.Background(), testEntries("a.log", "one"))
+
+	if n := len(srv.received()); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
+
+func TestWorkerStopsOnClientError(t *testing.T) {
+	srv := newStubServer(t, stubReply{status: http.StatusBadRequest, body: "bad labels"})
+	w := testWorker(t, SinkConfig{Type: "loki", URL: srv.URL})
+	w.send(context.Background(), testEntries("a.log", "one"))
+
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestWorkerGivesUpAfterMaxRetries(t *testing.T) {
+	srv := newStubServer(t, stubReply{status: http.StatusServiceUnavailable})
+	w := testWorker(t, SinkConfig{Type: "loki", URL: srv.URL, MaxRetries: 2})
+	w.send(context.Background(), testEntries("a.log", "one"))
+
+	if n := len(srv.received()); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
+
+// bulkDocs liefert die Meldungen der Dokumente einer Bulk-Anfrage
+func bulkDocs(t *testing.T, body []byte) []string {
+	var messages []string
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	for i := 1; i < len(lines); i += 2 {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &doc); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, doc["message"].(string))
+	}
+	return messages
+}
+
+func TestElasticsearchRetriesOnlyRejectedDocuments(t *testing.T) {
+	srv := newStubServer(t,
+		stubReply{status: http.StatusOK, body: `{"errors":true,"items":[
+			{"index":{"status":201}},
+			{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},
+			{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`},
+		stubReply{status: http.StatusOK, body: `{"errors":false,"items":[{"index":{"status":201}}]}`},
+	)
+	w := testWorker(t, SinkConfig{Type: "elasticsearch", URL: srv.URL})
+	w.send(context.Background(), testEntries("a.log", "accepted", "busy", "invalid"))
+
+	requests := srv.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if docs := bulkDocs(t, requests[0]); len(docs) != 3 {
+		t.Errorf("first request: got %v", docs)
+	}
+	if docs := bulkDocs(t, requests[1]); len(docs) != 1 || docs[0] != "busy" {
+		t.Errorf("retry: got %v, want [busy]", docs)
+	}
+}
+
+func TestElasticsearchDocumentFields(t *testing.T) {
+	srv := newStubServer(t, stubReply{status: http.StatusOK, body: `{"errors":false}`})
+	sink, _ := newSink(SinkConfig{Type: "elasticsearch", URL: srv.URL, Index: "test"})
+	if err := sink.Send(context.Background(), testEntries("a.log", "one")); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(srv.received()[0])), "\n")
+	var action map[string]map[string]string
+	var doc map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &action)
+	json.Unmarshal([]byte(lines[1]), &doc)
+	if action["index"]["_index"] != "test" {
+		t.Errorf("action %v", action)
+	}
+	if doc["source"] != "a.log" || doc["parser"] != "apache" {
+		t.Errorf("document %v", doc)
+	}
+}
+
+func TestWorkerBatches(t *testing.T) {
+	srv := newStubServer(t, stubReply{status: http.StatusOK, body: `{"errors":false}`})
+	w := testWorker(t, SinkConfig{Type: "elasticsearch", URL: srv.URL, BatchSize: 2, FlushInterval: "1h"})
+
+	for _, e := range testEntries("a.log", "1", "2", "3", "4", "5") {
+		w.enqueue(e)
+	}
+	close(w.entries)
+	w.run(context.Background())
+
+	var sizes []int
+	for _, body := range srv.received() {
+		sizes = append(sizes, len(bulkDocs(t, body)))
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("got batches %v, want [2 2 1]", sizes)
+	}
+}
+
+func TestWorkerDropsWhenQueueFull(t *testing.T) {
+	w := testWorker(t, SinkConfig{Type: "loki", URL: "http://127.0.0.1:1", BatchSize: 1})
+	for _, e := range testEntries("a.log", strings.Split(strings.Repeat("x", queueBatches+5), "")...) {
+		w.enqueue(e)
+	}
+	if n := w.dropped.Load(); n != 5 {
+		t.Errorf("dropped %d entries, want 5", n)
+	}
+}
+
+func TestGELFUDPChunks(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, _ := newSink(SinkConfig{Type: "gelf", Address: conn.LocalAddr().String()})
+	defer sink.Close()
+	long := strings.Repeat("a", 3*gelfChunkSize)
+	if err := sink.Send(context.Background(), testEntries("a.log", long)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunks: 0x1e 0x0f, 8 Byte ID, Nummer, Anzahl, Daten
+	var data []byte
+	buf := make([]byte, 2*gelfChunkSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for seq, count := 0, 1; seq < count; seq++ {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunk := buf[:n]
+		if n > gelfChunkSize || !bytes.HasPrefix(chunk, []byte{0x1e, 0x0f}) || int(chunk[10]) != seq {
+			t.Fatalf("chunk %d: invalid header % x", seq, chunk[:12])
+		}
+		count = int(chunk[11])
+		data = append(data, chunk[12:]...)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg["short_message"] != long || msg["_source"] != "a.log" || msg["_parser"] != "apache" {
+		t.Errorf("message fields: source %v, parser %v", msg["_source"], msg["_parser"])
+	}
+}
+
+func TestGELFTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var messages []string
+		for len(messages) < 2 {
+			msg, err := r.ReadBytes(0)
+			if err != nil {
+				break
+			}
+			messages = append(messages, string(bytes.TrimSuffix(msg, []byte{0})))
+		}
+		received <- messages
+	}()
+
+	sink, _ := newSink(SinkConfig{Type: "gelf", Address: ln.Addr().String(), Protocol: "tcp"})
+	defer sink.Close()
+	if err := sink.Send(context.Background(), testEntries("a.log", "one", "two")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case messages := <-received:
+		if len(messages) != 2 {
+			t.Fatalf("got %d messages, want 2", len(messages))
+		}
+		for i, want := range []string{"one", "two"} {
+			var msg map[string]interface{}
+			if err := json.Unmarshal([]byte(messages[i]), &msg); err != nil || msg["short_message"] != want {
+				t.Errorf("message %d: %s", i, messages[i])
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no messages received")
+	}
+}
+
+func TestGELFTCPStalledPeer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// Der Empfänger nimmt die Verbindung an, liest aber nie
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	sink, _ := newSink(SinkConfig{Type: "gelf", Address: ln.Addr().String(), Protocol: "tcp"})
+	defer sink.Close()
+	// Mehr, als die Puffer des Betriebssystems aufnehmen
+	messages := make([]string, 64)
+	for i := range messages {
+		messages[i] = strings.Repeat("a", 1<<20)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = sink.Send(ctx, testEntries("a.log", messages...))
+	if err == nil {
+		t.Fatal("send to stalled peer succeeded")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("send returned after %v", d)
+	}
+}
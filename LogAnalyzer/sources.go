@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// Maximale Länge einer Log-Zeile (Nextcloud-Zeilen können sehr lang werden)
+const maxLineSize = 1024 * 1024
+
+// Maximale Anzahl gepufferter Zeilen einer laufenden Quelle
+const maxBufferedLines = 100000
+
+// lineBuffer sammelt die Zeilen einer laufenden Quelle (Kommando oder stdin)
+type lineBuffer struct {
+	mu     sync.Mutex
+	lines  []string
+	base   int // Anzahl bereits verworfener Zeilen
+	notify chan struct{}
+	done   bool
+	err    error
+	cancel context.CancelFunc
+}
+
+func newLineBuffer() *lineBuffer {
+	return &lineBuffer{notify: make(chan struct{})}
+}
+
+func (b *lineBuffer) append(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines = append(b.lines, line)
+	if len(b.lines) > maxBufferedLines {
+		drop := len(b.lines) - maxBufferedLines
+		b.lines = append([]string(nil), b.lines[drop:]...)
+		b.base += drop
+	}
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+func (b *lineBuffer) finish(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done = true
+	b.err = err
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// since liefert alle Zeilen ab Position pos, die neue Position, den Status der
+// Quelle und einen Kanal, der bei neuen Zeilen geschlossen wird
+func (b *lineBuffer) since(pos int) (lines []string, next int, done bool, err error, notify <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if pos < b.base {
+		pos = b.base
+	}
+	lines = append(lines, b.lines[pos-b.base:]...)
+	return lines, b.base + len(b.lines), b.done, b.err, b.notify
+}
+
+// Close beendet den zugehörigen Prozess (falls vorhanden)
+func (b *lineBuffer) Close() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+// newLineScanner erstellt einen Scanner, der auch lange Zeilen verarbeitet
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return scanner
+}
+
+func (b *lineBuffer) readFrom(r io.Reader) error {
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		b.append(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// startCommand startet ein Kommando und sammelt stdout und stderr zeilenweise
+func startCommand(command string) (*lineBuffer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := shellCommand(ctx, command)
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	b := newLineBuffer()
+	b.cancel = cancel
+
+	go func() {
+		err := cmd.Wait()
+		pw.CloseWithError(err)
+	}()
+
+	go func() {
+		err := b.readFrom(pr)
+		if ctx.Err() != nil {
+			// Prozess wurde absichtlich beendet
+			err = nil
+		}
+		b.finish(err)
+	}()
+
+	return b, nil
+}
+
+var (
+	stdinOnce   sync.Once
+	stdinBuffer *lineBuffer
+)
+
+// stdinLines liefert den Puffer für stdin. stdin kann nur einmal gelesen werden,
+// daher wird er für die gesamte Laufzeit geteilt.
+func stdinLines() *lineBuffer {
+	stdinOnce.Do(func() {
+		stdinBuffer = newLineBuffer()
+		go func() {
+			stdinBuffer.finish(stdinBuffer.readFrom(os.Stdin))
+		}()
+	})
+	return stdinBuffer
+}
+
+// isStdin prüft, ob die Quelle von stdin gelesen wird
+func (c LogConfig) isStdin() bool {
+	return c.Path == "-"
+}
+
+// Name liefert die Bezeichnung der Quelle für die Anzeige
+func (c LogConfig) Name() string {
+	switch {
+	case c.Command != "":
+		return c.Command
+	case c.isStdin():
+		return "stdin"
+	}
+	return c.Path
+}
+
+// openStream startet eine laufende Quelle (Kommando oder stdin)
+func openStream(c LogConfig) (*lineBuffer, error) {
+	if c.Command != "" {
+		return startCommand(c.Command)
+	}
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		return nil, errorf("source.stdinTerminal")
+	}
+	return stdinLines(), nil
+}
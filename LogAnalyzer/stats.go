@@ -0,0 +1,176 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Abstand, in dem die Statistiken der Quellen neu berechnet werden
+const statsInterval = 30 * time.Second
+
+// Für die Statistik wird nur das Ende einer Datei gelesen
+const statsTailSize = 4 << 20
+
+// Die Statistik umfasst die letzte Stunde; die Sparkline zeigt die
+// Einträge je Minute
+const (
+	statsWindow  = time.Hour
+	statsBuckets = 60
+)
+
+// Anzahl Quellen, deren Statistik gleichzeitig berechnet wird
+const statsWorkers = 2
+
+// statsSlots begrenzt die gleichzeitigen Berechnungen, auch wenn die nächste
+// Runde beginnt, bevor die vorige fertig ist
+var statsSlots = make(chan struct{}, statsWorkers)
+
+// sourceStats ist die Kurzstatistik einer Quelle in der Liste
+type sourceStats struct {
+	modTime   time.Time
+	size      int64
+	errors    int       // error und fatal in der letzten Stunde
+	warns     int       // warn in der letzten Stunde
+	perMinute []float64 // Einträge je Minute, die ältesten zuerst
+	detected  string    // erkannter Typ bei type auto
+	err       error
+}
+
+// statsMsg liefert die Statistik einer Quelle; index ist ihre Position in
+// der Liste
+type statsMsg struct {
+	index int
+	name  string
+	stats sourceStats
+}
+
+// statsTickMsg löst die nächste Berechnung aus
+type statsTickMsg struct{}
+
+// collectStats liest das Ende einer Datei und zählt die Einträge der letzten
+// Stunde. Anreicherungen sind dafür nicht nötig, Severity-Regeln gelten.
+func collectStats(cfg LogConfig, now time.Time) sourceStats {
+	stats := sourceStats{perMinute: make([]float64, statsBuckets)}
+	cfg.enricher = nil
+
+	file, err := os.Open(cfg.Path)
+	if err != nil {
+		stats.err = err
+		return stats
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		stats.err = err
+		return stats
+	}
+	stats.modTime = info.ModTime()
+	stats.size = info.Size()
+
+	offset := max(info.Size()-statsTailSize, 0)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		stats.err = err
+		return stats
+	}
+	reader, err := newEntryReader(cfg)
+	if err != nil {
+		stats.err = err
+		return stats
+	}
+
+	since := now.Add(-statsWindow)
+	bucket := statsWindow / statsBuckets
+	count := func(results []parsedLine) {
+		for _, p := range results {
+			t := p.entry.Timestamp
+			if p.err != nil || t.Before(since) || t.After(now) {
+				continue
+			}
+			switch p.entry.Severity {
+			case "error", "fatal":
+				stats.errors++
+			case "warn":
+				stats.warns++
+			}
+			if i := statsBuckets - 1 - int(now.Sub(t)/bucket); i >= 0 {
+				stats.perMinute[i]++
+			}
+		}
+	}
+
+	scanner := newLineScanner(file)
+	// Mitten im Text beginnt die erste Zeile unvollständig
+	if offset > 0 {
+		scanner.Scan()
+	}
+	for scanner.Scan() {
+		count(reader.add(scanner.Text()))
+	}
+	count(reader.flush())
+	stats.detected = reader.typ
+	if err := scanner.Err(); err != nil {
+		stats.err = err
+	} else if reader.err != nil {
+		stats.err = reader.err
+	}
+	return stats
+}
+
+// summary ist die zweite Zeile einer Quelle in der Liste
+func (s sourceStats) summary(cfg *Config) string {
+	if s.err != nil {
+		return tr("stats.error", s.err)
+	}
+	modified := cfg.displayTime(s.modTime).Format("02.01.2006 15:04")
+	return tr("stats.summary", modified, formatBytes(s.size), s.errors, s.warns, sparkline(s.perMinute))
+}
+
+// refreshStats berechnet die Statistiken aller Dateien im Hintergrund.
+// Kommandos und stdin haben keine; Quellen mit Plugin auch nicht, damit nicht
+// alle 30 Sekunden deren Prozesse mit Zeilen belegt werden.
+func refreshStats(items []list.Item) tea.Cmd {
+	var cmds []tea.Cmd
+	for i, item := range items {
+		item, ok := item.(logFileItem)
+		if !ok || item.config.Command != "" || item.config.isStdin() || item.config.plugin != nil {
+			continue
+		}
+		cfg := item.config
+		// Einen bereits erkannten Typ nicht erneut erkennen
+		if cfg.autoDetect() && item.detected != "" {
+			cfg.Type = item.detected
+		}
+		cmds = append(cmds, func() tea.Msg {
+			statsSlots <- struct{}{}
+			defer func() { <-statsSlots }()
+			return statsMsg{index: i, name: cfg.Name(), stats: collectStats(cfg, time.Now())}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+func statsTick() tea.Cmd {
+	return tea.Tick(statsInterval, func(time.Time) tea.Msg {
+		return statsTickMsg{}
+	})
+}
+
+// showStats übernimmt die Statistik in die Liste. Ein dabei erkannter Typ
+// wird gemerkt, damit die nächste Runde nicht erneut erkennt.
+func (m *model) showStats(msg statsMsg) {
+	items := m.list.Items()
+	if msg.index >= len(items) {
+		return
+	}
+	if item, ok := items[msg.index].(logFileItem); ok && item.config.Name() == msg.name {
+		item.stats = msg.stats.summary(m.config)
+		if item.config.autoDetect() && item.detected == "" {
+			item.detected = msg.stats.detected
+		}
+		m.list.SetItem(msg.index, item)
+	}
+}
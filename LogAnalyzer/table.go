@@ -0,0 +1,324 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ColumnConfig beschreibt eine Spalte der Tabellenansicht
+type ColumnConfig struct {
+	Field string `yaml:"field"`           // time, source, severity, message oder ein Metadata-Schlüssel
+	Width int    `yaml:"width,omitempty"` // 0 nutzt die restliche Breite
+}
+
+// Spalten, wenn für die Quelle keine konfiguriert sind
+var defaultColumns = []ColumnConfig{
+	{Field: "time", Width: 16},
+	{Field: "severity", Width: 5},
+	{Field: "message"},
+}
+
+// Felder eines LogEntry, die als Spalte gewählt werden können
+var entryFields = []string{"time", "source", "severity", "message"}
+
+// Grenzen für die Spaltenbreite
+const (
+	minColumnWidth  = 3
+	flexColumnWidth = 20 // Mindestbreite der Spalte mit Breite 0
+)
+
+const columnSeparator = " │ "
+
+// tableState hält Spalten und Sortierung der Tabellenansicht
+type tableState struct {
+	columns  []ColumnConfig
+	selected int
+	sortBy   int // -1: Reihenfolge der Quelle
+	sortDesc bool
+}
+
+func newTableState(cfg LogConfig) tableState {
+	columns := cfg.Columns
+	if len(columns) == 0 {
+		columns = defaultColumns
+	}
+	return tableState{
+		columns: append([]ColumnConfig(nil), columns...),
+		sortBy:  -1,
+	}
+}
+
+// fieldValue liefert den Wert eines Feldes als Text
+func (m *model) fieldValue(e LogEntry, field string) string {
+	switch field {
+	case "time":
+		return m.config.displayTime(e.Timestamp).Format("02.01.2006 15:04")
+	case "source":
+		return e.Source
+	case "severity":
+		return e.Severity
+	case "message":
+		return e.Message
+	}
+	return e.Metadata[field]
+}
+
+// lessField vergleicht zwei Einträge nach einem Feld. Zeit und Level werden
+// nach ihrer Bedeutung sortiert, Zahlen numerisch, alles andere als Text.
+func lessField(a, b LogEntry, field string) bool {
+	switch field {
+	case "time":
+		return a.Timestamp.Before(b.Timestamp)
+	case "severity":
+		return levelOrder[a.Severity] < levelOrder[b.Severity]
+	case "source":
+		return a.Source < b.Source
+	case "message":
+		return a.Message < b.Message
+	}
+	va, vb := a.Metadata[field], b.Metadata[field]
+	na, errA := strconv.ParseFloat(va, 64)
+	nb, errB := strconv.ParseFloat(vb, 64)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return va < vb
+}
+
+// sortedEntries liefert die Einträge in der Sortierung der Tabelle
+func (m *model) sortedEntries() []LogEntry {
+	t := m.table
+	if t.sortBy < 0 || t.sortBy >= len(t.columns) {
+		return m.entries
+	}
+	field := t.columns[t.sortBy].Field
+	entries := append([]LogEntry(nil), m.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if t.sortDesc {
+			return lessField(entries[j], entries[i], field)
+		}
+		return lessField(entries[i], entries[j], field)
+	})
+	return entries
+}
+
+// columnWidths verteilt die verfügbare Breite; Spalten mit Breite 0 teilen
+// sich den Rest
+func (m *model) columnWidths() []int {
+	// Rahmen und Innenabstand des Viewports sowie der Einzug der Zeilen
+	available := m.viewport.Width - m.viewport.Style.GetHorizontalFrameSize() - 1
+
+	widths := make([]int, len(m.table.columns))
+	used, flex := 0, 0
+	for i, c := range m.table.columns {
+		widths[i] = c.Width
+		used += c.Width
+		if c.Width == 0 {
+			flex++
+		}
+	}
+	used += len(columnSeparator) * (len(widths) - 1)
+
+	if flex > 0 {
+		rest := (available - used) / flex
+		if rest < flexColumnWidth {
+			rest = flexColumnWidth
+		}
+		for i := range widths {
+			if widths[i] == 0 {
+				widths[i] = rest
+			}
+		}
+	}
+	return widths
+}
+
+// fitCell kürzt bzw. füllt einen Text auf die Spaltenbreite
+func fitCell(s string, width int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	s = ansi.Truncate(s, width, "…")
+	if pad := width - lipgloss.Width(s); pad > 0 {
+		s += strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+// renderTable rendert Kopfzeile und Einträge als Tabelle
+func (m *model) renderTable() []string {
+	t := m.table
+	widths := m.columnWidths()
+
+	header := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		title := c.Field
+		if i == t.sortBy {
+			if t.sortDesc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		cell := fitCell(title, widths[i])
+		if i == t.selected {
+			header[i] = selectedStyle.Render(cell)
+		} else {
+			header[i] = lipgloss.NewStyle().Bold(true).Render(cell)
+		}
+	}
+	lines := []string{logLineStyle.Render(strings.Join(header, helpStyle.Render(columnSeparator)))}
+
+	sourceStyle := lipgloss.NewStyle().Foreground(sourceColor(m.currentLog.Color)).Bold(true)
+	for _, e := range m.sortedEntries() {
+		e = m.config.redact(e)
+		cells := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			cell := fitCell(m.fieldValue(e, c.Field), widths[i])
+			switch c.Field {
+			case "severity":
+				cell = lipgloss.NewStyle().Foreground(severityColor(e.Severity)).Bold(true).Render(cell)
+			case "source":
+				cell = sourceStyle.Render(cell)
+			}
+			cells[i] = cell
+		}
+		lines = append(lines, logLineStyle.Render(strings.Join(cells, helpStyle.Render(columnSeparator))))
+	}
+	return lines
+}
+
+// moveColumn wählt die nächste bzw. vorherige Spalte
+func (t *tableState) moveColumn(delta int) {
+	t.selected = (t.selected + delta + len(t.columns)) % len(t.columns)
+}
+
+// resizeColumn ändert die Breite der gewählten Spalte. Eine flexible Spalte
+// erhält dabei zunächst eine feste Breite.
+func (m *model) resizeColumn(delta int) {
+	c := &m.table.columns[m.table.selected]
+	if c.Width == 0 {
+		c.Width = m.columnWidths()[m.table.selected]
+	}
+	c.Width += delta
+	if c.Width < minColumnWidth {
+		c.Width = minColumnWidth
+	}
+}
+
+// toggleSort sortiert nach der gewählten Spalte: aufsteigend, absteigend, aus
+func (t *tableState) toggleSort() {
+	switch {
+	case t.sortBy != t.selected:
+		t.sortBy = t.selected
+		t.sortDesc = false
+	case !t.sortDesc:
+		t.sortDesc = true
+	default:
+		t.sortBy = -1
+	}
+}
+
+// addColumn fügt das nächste noch nicht angezeigte Feld hinzu: zuerst die
+// Felder des LogEntry, danach die Metadata-Schlüssel der geladenen Einträge
+func (m *model) addColumn() {
+	shown := map[string]bool{}
+	for _, c := range m.table.columns {
+		shown[c.Field] = true
+	}
+
+	candidates := append([]string(nil), entryFields...)
+	var keys []string
+	seen := map[string]bool{}
+	for _, e := range m.entries {
+		for k := range e.Metadata {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	candidates = append(candidates, keys...)
+
+	for _, field := range candidates {
+		if !shown[field] {
+			column := ColumnConfig{Field: field, Width: 15}
+			pos := m.table.selected + 1
+			m.table.columns = append(m.table.columns[:pos], append([]ColumnConfig{column}, m.table.columns[pos:]...)...)
+			m.table.selected = pos
+			if m.table.sortBy >= pos {
+				m.table.sortBy++
+			}
+			return
+		}
+	}
+}
+
+// removeColumn entfernt die gewählte Spalte (mindestens eine bleibt)
+func (t *tableState) removeColumn() {
+	if len(t.columns) <= 1 {
+		return
+	}
+	if t.sortBy == t.selected {
+		t.sortBy = -1
+	} else if t.sortBy > t.selected {
+		t.sortBy--
+	}
+	t.columns = append(t.columns[:t.selected], t.columns[t.selected+1:]...)
+	if t.selected >= len(t.columns) {
+		t.selected = len(t.columns) - 1
+	}
+}
+
+// updateTable verarbeitet die Tasten der Tabellenansicht. Liefert false, wenn
+// die Taste nicht zur Tabelle gehört.
+func (m *model) updateTable(msg tea.KeyMsg) bool {
+	switch {
+	case key.Matches(msg, m.keys.PrevColumn):
+		m.table.moveColumn(-1)
+	case key.Matches(msg, m.keys.NextColumn):
+		m.table.moveColumn(1)
+	case key.Matches(msg, m.keys.Narrower):
+		m.resizeColumn(-2)
+	case key.Matches(msg, m.keys.Wider):
+		m.resizeColumn(2)
+	case key.Matches(msg, m.keys.Sort):
+		m.table.toggleSort()
+	case key.Matches(msg, m.keys.AddColumn):
+		m.addColumn()
+	case key.Matches(msg, m.keys.DelColumn):
+		m.table.removeColumn()
+	case key.Matches(msg, m.keys.Save):
+		m.saveColumns()
+	default:
+		return false
+	}
+	return true
+}
+
+// saveColumns speichert das Spaltenlayout der aktuellen Quelle in der config.yaml
+func (m *model) saveColumns() {
+	if m.view != nil {
+		v := *m.view
+		v.Columns = append([]ColumnConfig(nil), m.table.columns...)
+		m.saveView(v)
+		return
+	}
+	index := m.config.sourceIndex(m.currentLog)
+	if index < 0 {
+		m.notice = tr("table.sourceMissing")
+		return
+	}
+	if err := m.config.saveSourceField(index, "columns", m.table.columns); err != nil {
+		m.notice = tr("table.saveError", err)
+		return
+	}
+	m.config.Logs[index].Columns = append([]ColumnConfig(nil), m.table.columns...)
+	m.currentLog.Columns = m.config.Logs[index].Columns
+	m.notice = tr("table.saved", m.config.path)
+}
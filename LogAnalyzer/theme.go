@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// theme enthält alle Farben der Oberfläche. Jede Farbe hat Varianten für
+// True-Color-, 256- und 16-Farben-Terminals.
+type theme struct {
+	title     lipgloss.TerminalColor
+	text      lipgloss.TerminalColor
+	selection lipgloss.TerminalColor // Hintergrund der Auswahl
+	muted     lipgloss.TerminalColor
+	border    lipgloss.TerminalColor
+	raw       lipgloss.TerminalColor
+	failure   lipgloss.TerminalColor
+
+	colors   map[string]lipgloss.TerminalColor // Namen für color: in der config.yaml
+	severity map[string]lipgloss.TerminalColor
+}
+
+// tc erstellt eine Farbe mit Varianten für alle Farbtiefen
+func tc(trueColor, ansi256, ansi string) lipgloss.CompleteColor {
+	return lipgloss.CompleteColor{TrueColor: trueColor, ANSI256: ansi256, ANSI: ansi}
+}
+
+// Dunkles Theme (Dracula), bisher die einzigen Farben
+func darkTheme() theme {
+	colors := map[string]lipgloss.TerminalColor{
+		"red":     tc("#ff5555", "203", "9"),
+		"green":   tc("#50fa7b", "84", "10"),
+		"yellow":  tc("#f1fa8c", "228", "11"),
+		"blue":    tc("#6e9bf5", "69", "12"),
+		"magenta": tc("#ff79c6", "212", "13"),
+		"cyan":    tc("#8be9fd", "117", "14"),
+		"white":   tc("#f8f8f2", "231", "15"),
+		"orange":  tc("#ffb86c", "215", "3"),
+		"purple":  tc("#bd93f9", "141", "5"),
+		"gray":    tc("#6272a4", "61", "8"),
+	}
+	return theme{
+		title:     colors["cyan"],
+		text:      colors["white"],
+		selection: tc("#44475a", "238", "8"),
+		muted:     colors["gray"],
+		border:    tc("#44475a", "238", "8"),
+		raw:       colors["orange"],
+		failure:   colors["red"],
+		colors:    colors,
+		severity: map[string]lipgloss.TerminalColor{
+			"debug": colors["gray"],
+			"info":  colors["green"],
+			"warn":  colors["yellow"],
+			"error": colors["red"],
+			"fatal": colors["magenta"],
+		},
+	}
+}
+
+// Helles Theme mit dunkleren, auf weißem Hintergrund lesbaren Farben
+func lightTheme() theme {
+	colors := map[string]lipgloss.TerminalColor{
+		"red":     tc("#d70000", "160", "1"),
+		"green":   tc("#00875f", "29", "2"),
+		"yellow":  tc("#af8700", "136", "3"),
+		"blue":    tc("#005fd7", "26", "4"),
+		"magenta": tc("#af00af", "127", "5"),
+		"cyan":    tc("#00879f", "31", "6"),
+		"white":   tc("#303030", "236", "0"), // Standard-Textfarbe, daher dunkel
+		"orange":  tc("#d75f00", "166", "3"),
+		"purple":  tc("#5f00d7", "56", "5"),
+		"gray":    tc("#6c6c6c", "242", "8"),
+	}
+	return theme{
+		title:     colors["blue"],
+		text:      colors["white"],
+		selection: tc("#d0d0d0", "252", "7"),
+		muted:     colors["gray"],
+		border:    tc("#bcbcbc", "250", "7"),
+		raw:       colors["orange"],
+		failure:   colors["red"],
+		colors:    colors,
+		severity: map[string]lipgloss.TerminalColor{
+			"debug": colors["gray"],
+			"info":  colors["green"],
+			"warn":  colors["orange"],
+			"error": colors["red"],
+			"fatal": colors["magenta"],
+		},
+	}
+}
+
+// Aktives Theme, gesetzt durch applyTheme
+var currentTheme = darkTheme()
+
+// Themes für theme: in der config.yaml. "auto" wählt anhand des
+// Terminal-Hintergrunds.
+var themes = map[string]func() theme{
+	"":      darkTheme,
+	"dark":  darkTheme,
+	"light": lightTheme,
+	"auto": func() theme {
+		if lipgloss.HasDarkBackground() {
+			return darkTheme()
+		}
+		return lightTheme()
+	},
+}
+
+// applyTheme setzt die Farben aller Styles. Mit NO_COLOR wird ohne Farben
+// dargestellt, die Auswahl dann invertiert.
+func applyTheme(t theme) {
+	currentTheme = t
+	noColor := os.Getenv("NO_COLOR") != ""
+	if noColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.title).
+		MarginLeft(2)
+
+	selectedStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.text).
+		Background(t.selection)
+	if noColor {
+		selectedStyle = lipgloss.NewStyle().Bold(true).Reverse(true)
+	}
+
+	helpStyle = lipgloss.NewStyle().
+		Foreground(t.muted)
+
+	rawLineStyle = lipgloss.NewStyle().
+		Foreground(t.raw).
+		Italic(true)
+
+	parseErrorStyle = lipgloss.NewStyle().
+		Foreground(t.failure).
+		Bold(true)
+}
+
+// listItemStyles leitet die Styles der Quellenliste vom Theme ab, statt die
+// Standardfarben von bubbles zu verwenden
+func listItemStyles(t theme) list.DefaultItemStyles {
+	s := list.NewDefaultItemStyles()
+	s.NormalTitle = s.NormalTitle.Foreground(t.text)
+	s.NormalDesc = s.NormalDesc.Foreground(t.muted)
+	s.SelectedTitle = s.SelectedTitle.Foreground(t.title).BorderForeground(t.title).Bold(true)
+	s.SelectedDesc = s.SelectedDesc.Foreground(t.text).BorderForeground(t.title)
+	s.DimmedTitle = s.DimmedTitle.Foreground(t.muted)
+	s.DimmedDesc = s.DimmedDesc.Foreground(t.border)
+	return s
+}
+
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// parseColor prüft eine Farbe aus der config.yaml: ein Name des Themes, ein
+// Hex-Wert wie "#ff8800" oder eine ANSI-Nummer von 0 bis 255
+func parseColor(value string) (lipgloss.TerminalColor, bool) {
+	if value == "" {
+		return lipgloss.NoColor{}, true
+	}
+	if c, ok := currentTheme.colors[value]; ok {
+		return c, true
+	}
+	if hexColorRegex.MatchString(value) {
+		return lipgloss.Color(value), true
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(value), true
+	}
+	return nil, false
+}
+
+// sourceColor liefert die Farbe einer Quelle; ungültige Werte bleiben ohne Farbe
+func sourceColor(value string) lipgloss.TerminalColor {
+	c, ok := parseColor(value)
+	if !ok {
+		return lipgloss.NoColor{}
+	}
+	return c
+}
+
+// severityColor liefert die Farbe eines Levels
+func severityColor(severity string) lipgloss.TerminalColor {
+	if c, ok := currentTheme.severity[severity]; ok {
+		return c
+	}
+	return currentTheme.text
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// timeParser parst die Zeitstempel einer Quelle mit den konfigurierten
+// Formaten und der Zeitzone der Quelle
+type timeParser struct {
+	layouts []string
+	loc     *time.Location
+}
+
+func newTimeParser(cfg LogConfig) timeParser {
+	var p timeParser
+	if cfg.TimeFormat != "" {
+		p.layouts = append(p.layouts, cfg.TimeFormat)
+	}
+	if cfg.LogDateFormat != "" {
+		// Ungültige Formate weist bereits loadConfig zurück
+		layout, _ := phpDateLayout(cfg.LogDateFormat)
+		p.layouts = append(p.layouts, layout)
+	}
+	p.loc = time.Local
+	if loc, err := loadLocation(cfg.Timezone); err == nil {
+		p.loc = loc
+	}
+	return p
+}
+
+// parse probiert zuerst die konfigurierten Formate, danach die Standardformate
+// des Parsers. Zeitstempel ohne Zonenangabe gelten in der Zeitzone der Quelle.
+func (p timeParser) parse(value string, defaults ...string) (time.Time, error) {
+	for _, layouts := range [][]string{p.layouts, defaults} {
+		for _, layout := range layouts {
+			if t, err := time.ParseInLocation(layout, value, p.loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, errorf("time.unknownFormat", value)
+}
+
+// loadLocation löst "local", "UTC" oder einen Zonennamen wie "Europe/Berlin" auf
+func loadLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// Übersetzung der PHP date()-Zeichen, wie sie Nextcloud in logdateformat nutzt
+var phpDateTokens = map[rune]string{
+	'd': "02",
+	'D': "Mon",
+	'j': "2",
+	'l': "Monday",
+	'm': "01",
+	'n': "1",
+	'M': "Jan",
+	'F': "January",
+	'y': "06",
+	'Y': "2006",
+	'a': "pm",
+	'A': "PM",
+	'g': "3",
+	'h': "03",
+	'G': "15",
+	'H': "15",
+	'i': "04",
+	's': "05",
+	'u': "000000",
+	'v': "000",
+	'T': "MST",
+	'O': "-0700",
+	'P': "-07:00",
+	'p': "Z07:00",
+	'c': time.RFC3339,
+	'r': time.RFC1123Z,
+}
+
+// phpDateLayout wandelt ein PHP-Datumsformat in ein Go-Layout um. Buchstaben
+// ohne Entsprechung in Go (z.B. U, e, S oder N) ergeben einen Fehler, da sie
+// sonst als Text im Layout stünden und kein Zeitstempel mehr passen würde.
+func phpDateLayout(format string) (string, error) {
+	var b strings.Builder
+	escaped := false
+	for _, r := range format {
+		if escaped {
+			b.WriteRune(r)
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		if token, ok := phpDateTokens[r]; ok {
+			b.WriteString(token)
+		} else if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return "", errorf("config.phpToken", string(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// displayTime rechnet einen Zeitstempel in die Anzeige-Zeitzone um
+func (c *Config) displayTime(t time.Time) time.Time {
+	if c.displayLoc == nil {
+		return t.In(time.Local)
+	}
+	return t.In(c.displayLoc)
+}
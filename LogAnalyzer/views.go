@@ -0,0 +1,332 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// ViewConfig ist eine gespeicherte Ansicht: Quellen mit Filtern und
+// Spaltenlayout, die in der Liste neben den Quellen erscheint
+type ViewConfig struct {
+	Name    string         `yaml:"name"`
+	Sources []string       `yaml:"sources,omitempty"` // Namen der Quellen, leer für alle Dateien
+	Level   string         `yaml:"level,omitempty"`   // Mindestlevel
+	Hide    []string       `yaml:"hide,omitempty"`    // einzeln ausgeblendete Level
+	Since   string         `yaml:"since,omitempty"`   // z.B. "24h", relativ zum Öffnen
+	From    string         `yaml:"from,omitempty"`    // RFC3339 oder 2006-01-02T15:04
+	To      string         `yaml:"to,omitempty"`
+	Text    string         `yaml:"text,omitempty"`  // Text in Meldung oder Metadaten
+	Regex   string         `yaml:"regex,omitempty"` // regulärer Ausdruck für die Meldung
+	Columns []ColumnConfig `yaml:"columns,omitempty"`
+}
+
+// viewFilter ist der aufgelöste Filter einer Ansicht
+type viewFilter struct {
+	entryFilter
+	re *regexp.Regexp
+}
+
+// filter löst Zeitraum und Ausdrücke der Ansicht auf; since gilt ab now
+func (v ViewConfig) filter(cfg *Config, now time.Time) (viewFilter, error) {
+	f := viewFilter{entryFilter: entryFilter{text: strings.ToLower(v.Text)}}
+	var err error
+	if f.from, err = parseQueryTime(v.From, cfg.displayLoc); err != nil {
+		return f, err
+	}
+	if f.to, err = parseQueryTime(v.To, cfg.displayLoc); err != nil {
+		return f, err
+	}
+	if v.Since != "" {
+		since, err := time.ParseDuration(v.Since)
+		if err != nil {
+			return f, errorf("view.since", v.Since)
+		}
+		f.from = now.Add(-since)
+	}
+	if v.Regex != "" {
+		if f.re, err = regexp.Compile(v.Regex); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func (f viewFilter) match(e LogEntry) bool {
+	return f.entryFilter.match(e) && (f.re == nil || f.re.MatchString(e.Message))
+}
+
+// validate prüft eine Ansicht beim Laden der Konfiguration
+func (v ViewConfig) validate(cfg *Config) error {
+	if v.Level != "" {
+		if _, ok := levelOrder[v.Level]; !ok {
+			return errorf("view.level", v.Name, v.Level)
+		}
+	}
+	for _, name := range v.Sources {
+		if cfg.sourceIndex(LogConfig{Path: name}) < 0 {
+			return errorf("view.source", v.Name, name)
+		}
+	}
+	if _, err := v.filter(cfg, time.Now()); err != nil {
+		return errorf("view.invalid", v.Name, err)
+	}
+	return nil
+}
+
+// sources liefert die Quellen der Ansicht. Kommandos und stdin enden nicht
+// und werden übersprungen.
+func (v ViewConfig) sources(cfg *Config) []LogConfig {
+	var sources []LogConfig
+	for _, logCfg := range cfg.Logs {
+		if logCfg.Command != "" || logCfg.isStdin() {
+			continue
+		}
+		if len(v.Sources) == 0 {
+			sources = append(sources, logCfg)
+			continue
+		}
+		for _, name := range v.Sources {
+			if logCfg.Name() == name {
+				sources = append(sources, logCfg)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// logConfig liefert die Einstellungen, mit denen die Log-Ansicht die
+// Ansicht wie eine Quelle behandelt
+func (v ViewConfig) logConfig() LogConfig {
+	return LogConfig{Path: v.Name, LogLevel: v.Level, Columns: v.Columns}
+}
+
+// viewEntries liest alle Quellen der Ansicht und liefert die passenden
+// Einträge nach Zeit sortiert. Level filtert die Log-Ansicht selbst.
+func viewEntries(cfg *Config, v ViewConfig, now time.Time) ([]LogEntry, error) {
+	filter, err := v.filter(cfg, now)
+	if err != nil {
+		return nil, err
+	}
+	var all []LogEntry
+	for _, src := range v.sources(cfg) {
+		src.LogLevel = "debug"
+		entries, _, err := readEntries(src)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if filter.match(e) {
+				all = append(all, e)
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Timestamp.Before(all[j].Timestamp) })
+	return all, nil
+}
+
+// viewItem ist eine gespeicherte Ansicht in der Liste
+type viewItem struct {
+	view ViewConfig
+}
+
+func (i viewItem) FilterValue() string { return i.view.Name }
+func (i viewItem) Title() string       { return "★ " + i.view.Name }
+func (i viewItem) Description() string {
+	sources := tr("list.allSources")
+	if len(i.view.Sources) > 0 {
+		sources = strings.Join(i.view.Sources, ", ")
+	}
+	var filters []string
+	for _, f := range []string{i.view.Since, i.view.Text, i.view.Regex} {
+		if f != "" {
+			filters = append(filters, f)
+		}
+	}
+	return tr("list.view", sources, i.view.Level, strings.Join(filters, " "))
+}
+
+// readView liest die Einträge der geöffneten Ansicht
+func (m *model) readView() {
+	entries, err := viewEntries(m.config, *m.view, time.Now())
+	if err != nil {
+		m.showError(tr("logs.error", err))
+		return
+	}
+	// Wie bei laufenden Quellen die neuesten Einträge zeigen: ältere, die
+	// über maxLogEntries hinausgehen, werden nur gezählt
+	start, shown := len(entries), 0
+	for start > 0 && shown < maxLogEntries {
+		start--
+		if m.levels.shows(entries[start].Severity) {
+			shown++
+		}
+	}
+	for _, e := range entries[:start] {
+		m.levelCounts[e.Severity]++
+		if m.levels.shows(e.Severity) {
+			m.truncated = true
+		}
+	}
+	for _, e := range entries[start:] {
+		m.addParsed([]parsedLine{{entry: e}})
+	}
+	m.reader.parsed = len(entries)
+	m.renderLogs()
+}
+
+// currentView beschreibt die aktuelle Log-Ansicht mit Level und Spalten als
+// Ansicht. Filter einer geöffneten Ansicht werden übernommen.
+func (m *model) currentView(name string) ViewConfig {
+	v := ViewConfig{Sources: []string{m.currentLog.Name()}}
+	if m.view != nil {
+		v = *m.view
+	}
+	v.Name = name
+	v.Level = m.levels.min
+	v.Hide = nil
+	for _, level := range severityLevels {
+		if m.levels.hidden[level] {
+			v.Hide = append(v.Hide, level)
+		}
+	}
+	v.Columns = nil
+	if m.tableMode {
+		v.Columns = append([]ColumnConfig(nil), m.table.columns...)
+	}
+	return v
+}
+
+// startNaming fragt den Namen für die aktuelle Ansicht ab
+func (m *model) startNaming() tea.Cmd {
+	if m.view == nil && (m.currentLog.Command != "" || m.currentLog.isStdin()) {
+		m.notice = tr("view.stream")
+		m.renderLogs()
+		return nil
+	}
+	input := textinput.New()
+	input.Prompt = tr("view.prompt")
+	input.CharLimit = 64
+	if m.view != nil {
+		input.SetValue(m.view.Name)
+	}
+	m.nameInput = input
+	m.naming = true
+	return m.nameInput.Focus()
+}
+
+// updateNaming verarbeitet die Eingabe des Namens
+func (m model) updateNaming(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.naming = false
+		return m, nil
+	case tea.KeyEnter:
+		name := strings.TrimSpace(m.nameInput.Value())
+		if name == "" {
+			return m, nil
+		}
+		m.naming = false
+		m.saveView(m.currentView(name))
+		m.renderLogs()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	return m, cmd
+}
+
+// saveView speichert eine Ansicht in der config.yaml; eine gleichnamige wird
+// ersetzt. Die Liste zeigt sie danach sofort.
+func (m *model) saveView(v ViewConfig) {
+	err := m.config.editConfigFile(func(root *yaml.Node) error {
+		var node yaml.Node
+		if err := node.Encode(v); err != nil {
+			return err
+		}
+		// Listen kompakt schreiben, Spalten wie bei den Quellen je eine Zeile
+		for _, field := range []string{"sources", "hide"} {
+			if seq := mappingValue(&node, field); seq != nil {
+				seq.Style = yaml.FlowStyle
+			}
+		}
+		if columns := mappingValue(&node, "columns"); columns != nil {
+			for _, column := range columns.Content {
+				column.Style = yaml.FlowStyle
+			}
+		}
+
+		views := mappingValue(root, "views")
+		if views == nil {
+			views = &yaml.Node{}
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "views"}, views)
+		}
+		if views.Kind != yaml.SequenceNode {
+			*views = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		for i, existing := range views.Content {
+			if name := mappingValue(existing, "name"); name != nil && name.Value == v.Name {
+				views.Content[i] = &node
+				return nil
+			}
+		}
+		views.Content = append(views.Content, &node)
+		return nil
+	})
+	if err != nil {
+		m.notice = tr("view.saveError", err)
+		return
+	}
+
+	found := false
+	for i := range m.config.Views {
+		if m.config.Views[i].Name == v.Name {
+			m.config.Views[i] = v
+			found = true
+		}
+	}
+	if !found {
+		m.config.Views = append(m.config.Views, v)
+	}
+
+	found = false
+	items := m.list.Items()
+	for i, item := range items {
+		if vi, ok := item.(viewItem); ok && vi.view.Name == v.Name {
+			m.list.SetItem(i, viewItem{view: v})
+			found = true
+		}
+	}
+	if !found {
+		m.list.InsertItem(len(items), viewItem{view: v})
+	}
+
+	// Eine geöffnete Ansicht wird zur gespeicherten
+	if m.view != nil {
+		m.view = &v
+		m.currentLog = v.logConfig()
+	}
+	m.notice = tr("view.saved", v.Name, m.config.path)
+}
+
+// openView öffnet eine gespeicherte Ansicht in der Log-Ansicht
+func (m *model) openView(v ViewConfig) tea.Cmd {
+	m.view = &v
+	return m.loadLogFile(v.logConfig())
+}
+
+// viewListItems liefert die gespeicherten Ansichten für die Liste
+func viewListItems(cfg *Config) []list.Item {
+	items := make([]list.Item, len(cfg.Views))
+	for i, v := range cfg.Views {
+		items[i] = viewItem{view: v}
+	}
+	return items
+}